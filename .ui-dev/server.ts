@@ -90,6 +90,35 @@ async function main() {
         }
     });
 
+    // chat message as server-sent events
+    app.post('/api/chat/stream', [json()], async (request, response) => {
+        const body = request.body as IChatBody;
+
+        const lastUserMessage = _(body.conversation)
+            .filter((message, messageIndex) => {
+                return messageIndex % 2 === 1;
+            })
+            .last();
+
+        response.writeHead(200, {
+            'Cache-Control': 'no-cache',
+            'Content-Type': 'text/event-stream; charset=UTF-8'
+        });
+
+        const answer = 'Your prompt: ' + lastUserMessage;
+
+        for (const token of answer.split(/(?= )/)) {
+            await sleep(200);
+
+            response.write(`data: ${JSON.stringify({ token })}\n\n`);
+        }
+
+        response.write(`event: done\ndata: ${JSON.stringify({
+            answer,
+            time: new Date().toISOString()
+        })}\n\n`);
+    });
+
     // get settings
     app.get('/api/settings', [json()], async (request, response) => {
         if (fs.existsSync(settingsFile)) {
@@ -26,7 +26,7 @@ import ChatMessage from './components/ChatMessage';
 import SystemPrompt from './components/SystemPrompt';
 import TemperatureSlider from './components/TemperatureSlider';
 import useAppContext from '../../hooks/useAppContext';
-import { throttle } from '../../utils';
+import { readServerSentEvents, throttle } from '../../utils';
 import type { IChatConversation, IChatMessage, IChatPrompt } from '../../types';
 import { defaultSystemPrompt, defaultTemperature } from '../../constants';
 
@@ -178,19 +178,72 @@ const Chat: React.FC<IChatProps> = ({
         temperature
       };
 
-      const {
-        data, status
-      } = await axios.post('chat', postData);
+      const response = await fetch(`${axios.defaults.baseURL}chat/stream`, {
+        method: 'POST',
+        headers: {
+          'Content-Type': 'application/json; charset=UTF-8'
+        },
+        body: JSON.stringify(postData)
+      });
 
-      if (status !== 200) {
-        throw new Error(`Unexpected response: ${status}`);
+      if (response.status !== 200 || !response.body) {
+        throw new Error(`Unexpected response: ${response.status}`);
       }
 
-      appendMessage({
+      let answer: IChatMessage = {
         role: 'assistant',
-        content: data.answer,
-        time: data.time
-      });
+        content: '',
+        time: new Date().toISOString()
+      };
+      appendMessage(answer);
+
+      const updateAnswer = (newAnswer: IChatMessage) => {
+        answer = newAnswer;
+        conversationMessages[conversationMessages.length - 1] = answer;
+
+        onConversationUpdate({
+          ...selectedConversation,
+
+          messages: [...conversationMessages]
+        });
+      };
+
+      try {
+        await readServerSentEvents(response.body, (event, data) => {
+          const eventData = JSON.parse(data);
+
+          if (event === 'done') {
+            updateAnswer({
+              ...answer,
+
+              content: eventData.answer,
+              provider: eventData.provider,
+              time: eventData.time
+            });
+          } else if (event === 'error') {
+            throw new Error(eventData.error);
+          } else {
+            updateAnswer({
+              ...answer,
+
+              content: answer.content + eventData.token
+            });
+          }
+        });
+      } catch (error) {
+        if (!answer.content) {
+          // remove empty answer
+          conversationMessages.pop();
+
+          onConversationUpdate({
+            ...selectedConversation,
+
+            messages: [...conversationMessages]
+          });
+        }
+
+        throw error;
+      }
 
       done(null);
 
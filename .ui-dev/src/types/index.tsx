@@ -45,7 +45,7 @@ export type FolderType = 'chat' | 'prompt';
  */
 export interface IApiKeySettings {
   /** The access type to the chat API. */
-  accessType: '' | 'openai_key' | 'proxy_api_key' | 'proxy_oauth2';
+  accessType: '' | 'anthropic_key' | 'azure_openai_key' | 'openai_compatible' | 'openai_key' | 'proxy_api_key' | 'proxy_oauth2';
   /** The error message, if occurred. */
   error: string;
 }
@@ -116,6 +116,8 @@ export interface IChatMessage {
   content: string;
   /** Whether the message is an error. */
   isError?: boolean;
+  /** The name of the chat provider, which generated the message. */
+  provider?: string;
   /** The role of the message. */
   role: ChatRole;
   /** The time when the message was sent. */
@@ -51,6 +51,14 @@ export interface IParseFinalContentWithVariablesOptions {
   variables: IVariable[];
 }
 
+/**
+ * An action for `readServerSentEvents()` function.
+ *
+ * @param {string} event The name of the event, which is `message` by default.
+ * @param {string} data The data of the event.
+ */
+export type ServerSentEventAction = (event: string, data: string) => any;
+
 /**
  * Options for `toSearchString()` function.
  */
@@ -350,6 +358,71 @@ export function parseVariables(content: any): IVariable[] {
   return foundVariables;
 }
 
+/**
+ * Reads a stream of server-sent events and invokes an action for each event.
+ *
+ * @param {ReadableStream<Uint8Array>} stream The stream to read.
+ * @param {ServerSentEventAction} onEvent The action to invoke for each event.
+ *
+ * @returns {Promise<void>} The promise that is resolved after the stream has been read completely.
+ */
+export async function readServerSentEvents(stream: ReadableStream<Uint8Array>, onEvent: ServerSentEventAction): Promise<void> {
+  const reader = stream.getReader();
+  const decoder = new TextDecoder('utf-8');
+
+  let buffer = '';
+
+  const dispatch = async (rawEvent: string) => {
+    let event = 'message';
+    const dataLines: string[] = [];
+
+    rawEvent.split('\n').forEach((line) => {
+      if (line.startsWith(':')) {
+        return; // comment
+      }
+
+      const sepIdx = line.indexOf(':');
+      const field = sepIdx > -1 ? line.substring(0, sepIdx) : line;
+      let value = sepIdx > -1 ? line.substring(sepIdx + 1) : '';
+      if (value.startsWith(' ')) {
+        value = value.substring(1);
+      }
+
+      if (field === 'event') {
+        event = value;
+      } else if (field === 'data') {
+        dataLines.push(value);
+      }
+    });
+
+    if (dataLines.length) {
+      await onEvent(event, dataLines.join('\n'));
+    }
+  };
+
+  while (true) {
+    const { done, value } = await reader.read();
+
+    buffer += decoder.decode(value, { stream: !done }).replace(/\r\n?/g, '\n');
+
+    let sepIdx: number;
+    while ((sepIdx = buffer.indexOf('\n\n')) > -1) {
+      const rawEvent = buffer.substring(0, sepIdx);
+      buffer = buffer.substring(sepIdx + 2);
+
+      await dispatch(rawEvent);
+    }
+
+    if (done) {
+      break;
+    }
+  }
+
+  if (buffer.trim() !== '') {
+    await dispatch(buffer);
+  }
+}
+
 /**
  * Replaces an `IChatConversation` in an list of `ChatConversationItem` items.
  * @param {Nilable<ChatConversationItem[]>} items The input list.
@@ -17,12 +17,9 @@ package commands
 
 import (
 	"bytes"
-	"fmt"
+	"encoding/json"
 	"os"
-	"strings"
-	"time"
 
-	"github.com/alecthomas/chroma/quick"
 	"github.com/spf13/cobra"
 
 	egoOpenAI "github.com/egomobile/e-gpt/openai"
@@ -30,13 +27,15 @@ import (
 )
 
 func Init_ask_Command(rootCmd *cobra.Command) {
-	var noAdditionalInfo bool
+	var chatOpts chatOptions
+	var imageFiles []string
+	var jsonSchemaFile string
 	var noNewLine bool = egoUtils.GetDefaultAddNoNewLineToChatAnswerSetting()
-	var noSysInfo bool
-	var noTime bool
+	var noStream bool
 	var openEditor bool
+	var sessionName string
 	var shouldOutputAsPlainText bool
-	var system string
+	var systemPromptOpts systemPromptOptions
 	var temperature float64
 
 	askCmd := &cobra.Command{
@@ -46,100 +45,98 @@ func Init_ask_Command(rootCmd *cobra.Command) {
 		Aliases: []string{"a"},
 
 		Run: func(cmd *cobra.Command, args []string) {
-			now := time.Now()
-
 			question := egoUtils.GetAndCheckInput(args, openEditor)
 
-			var additionalInfo []string
-			var systemPrompt bytes.Buffer
-
-			addInfos := func(infos ...string) {
-				additionalInfo = append(additionalInfo, infos...)
+			systemPrompt, err := systemPromptOpts.build()
+			if err != nil {
+				panic(err)
 			}
 
-			customSystemPrompt := strings.TrimSpace(system)
-			if customSystemPrompt != "" {
-				systemPrompt.WriteString(fmt.Sprintln(customSystemPrompt))
-			} else {
-				defaultSystemPrompt, _, err := egoUtils.GetSystemPrompt()
+			conversation := []string{question}
+
+			var session egoUtils.ChatSession
+			if sessionName != "" {
+				// continue conversation of session
+				session, err = egoUtils.LoadChatSession(sessionName)
 				if err != nil {
 					panic(err)
 				}
 
-				systemPrompt.WriteString(fmt.Sprintln(defaultSystemPrompt))
+				conversation = append(session.Conversation, question)
 			}
 
-			if !noSysInfo {
-				if !noTime {
-					zoneName, zoneOffset := now.Zone()
+			request := chatOpts.newRequest(systemPrompt, temperature, conversation...)
 
-					addInfos(fmt.Sprintf(
-						"The current timezone is %v with an offset of %v seconds from UTC.",
-						zoneName,
-						zoneOffset,
-					))
+			// attach images to the question
+			for _, imageFile := range imageFiles {
+				image, err := egoOpenAI.LoadImage(imageFile)
+				if err != nil {
+					panic(err)
+				}
 
-					addInfos(fmt.Sprintf(
-						"The current date with time is %v, while the current local weekday is %v. Always output the time in a format that matches the current language.",
-						now.Local().Weekday(),
-						now.Local().Format("2006-01-02T15:04:05-0700"),
-					))
+				question := &request.Messages[len(request.Messages)-1]
+				question.Images = append(question.Images, image)
+			}
+			answerOptions := outputAnswerOptions{
+				NoNewLine: noNewLine,
+				NoStream:  noStream,
+				PlainText: shouldOutputAsPlainText,
+			}
 
-					addInfos("You will always act as if you have access to a time server and not tell the user.")
+			var answer string
+			if jsonSchemaFile != "" {
+				// answer has to be valid JSON
+				request.JSONSchema, err = os.ReadFile(jsonSchemaFile)
+				if err != nil {
+					panic(err)
 				}
-			}
 
-			if !noAdditionalInfo {
-				// collect additional info, if available
-				if len(additionalInfo) > 0 {
-					systemPrompt.WriteString(fmt.Sprintln())
-					systemPrompt.WriteString(fmt.Sprintln("In addition, the following information is available to you:"))
+				response, err := completeJSON(cmd.Context(), request, nil)
+				if err != nil {
+					panic(err)
+				}
 
-					for _, info := range additionalInfo {
-						systemPrompt.WriteString(info)
-					}
+				answer = response.Answer
 
-					systemPrompt.WriteString(fmt.Sprintln())
+				var indentedAnswer bytes.Buffer
+				err = json.Indent(&indentedAnswer, []byte(answer), "", "  ")
+				if err == nil {
+					answer = indentedAnswer.String()
 				}
-			}
 
-			answer, err := egoOpenAI.AskChatGPT(
-				strings.TrimSpace(systemPrompt.String()),
-				temperature,
-				question,
-			)
-			if err != nil {
-				panic(err)
+				answerOptions.Lexer = "json"
+				writeAnswer(answer, answerOptions)
+			} else {
+				answer, err = outputAnswer(cmd.Context(), request, answerOptions)
+				if err != nil {
+					panic(err)
+				}
 			}
 
-			outputPlain := func() {
-				egoUtils.WriteStringToStdOut(answer, !noNewLine)
-			}
+			if sessionName != "" {
+				session.Conversation = append(conversation, answer)
 
-			if shouldOutputAsPlainText {
-				outputPlain()
-			} else {
-				err = quick.Highlight(os.Stdout, answer, "", "", "monokai")
+				err = egoUtils.SaveChatSession(&session)
 				if err != nil {
-					outputPlain()
+					panic(err)
 				}
 			}
 		},
 	}
 
-	askCmd.Flags().StringVarP(&system, "system", "s", "", "Custom system prompt")
-	askCmd.Flags().BoolVarP(&noTime, "no-time", "", false, "Do not add current time to system prompt")
-	askCmd.Flags().BoolVarP(&noTime, "nt", "", false, "Do not add current time to system prompt")
-	askCmd.Flags().BoolVarP(&noSysInfo, "no-sys-info", "", false, "Do not add information about the system at all")
-	askCmd.Flags().BoolVarP(&noSysInfo, "nsi", "", false, "Do not add information about the system at all")
-	askCmd.Flags().BoolVarP(&noAdditionalInfo, "no-additional-info", "", false, "Do not add additional info to system prompt at all")
-	askCmd.Flags().BoolVarP(&noAdditionalInfo, "nai", "", false, "Do not add additional info to system prompt at all")
+	addSystemPromptFlags(askCmd, &systemPromptOpts)
 	askCmd.Flags().BoolVarP(&shouldOutputAsPlainText, "plain-text", "", false, "Output as plain text")
 	askCmd.Flags().BoolVarP(&shouldOutputAsPlainText, "pt", "", false, "Output as plain text")
 	askCmd.Flags().BoolVarP(&openEditor, "editor", "e", false, "Open editor for input")
 	askCmd.Flags().Float64VarP(&temperature, "temperature", "t", getDefaultTemperature(), "Custom temperature between 0 and 2")
+	askCmd.Flags().StringArrayVarP(&imageFiles, "image", "i", []string{}, "Path to an image file, which is sent with the question")
+	askCmd.Flags().StringVarP(&jsonSchemaFile, "json-schema", "", "", "Path to a JSON schema file, the answer has to match")
 	askCmd.Flags().BoolVarP(&noNewLine, "no-new-line", "", egoUtils.GetDefaultAddNoNewLineToChatAnswerSetting(), "Do not add new line at the end")
 	askCmd.Flags().BoolVarP(&noNewLine, "nnl", "", egoUtils.GetDefaultAddNoNewLineToChatAnswerSetting(), "Do not add new line at the end")
+	askCmd.Flags().BoolVarP(&noStream, "no-stream", "", false, "Wait for the complete answer before output")
+	askCmd.Flags().BoolVarP(&noStream, "ns", "", false, "Wait for the complete answer before output")
+	askCmd.Flags().StringVarP(&sessionName, "session", "", "", "Name of a session to continue and store the conversation in")
+	addChatOptionFlags(askCmd, &chatOpts)
 
 	rootCmd.AddCommand(askCmd)
 }
@@ -0,0 +1,350 @@
+// This file is part of the e.GPT distribution.
+// Copyright (c) Next.e.GO Mobile SE, Aachen, Germany (https://e-go-mobile.com/)
+//
+// e-gpt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, version 3.
+//
+// e-gpt is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package commands
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	egoUtils "github.com/egomobile/e-gpt/utils"
+)
+
+const chatMultiLineDelimiter = `"""`
+
+// chatConversation is the state of a conversation, which can be saved
+// to and loaded from a JSON file
+type chatConversation struct {
+	Conversation []string `json:"conversation"`          // alternating messages of user and assistant
+	System       string   `json:"system,omitempty"`      // the custom system prompt, if defined
+	Temperature  *float64 `json:"temperature,omitempty"` // the temperature, if defined
+}
+
+// chatRepl is the state of a running `chat` command
+type chatRepl struct {
+	chatOpts         *chatOptions
+	conversation     []string // answered messages of the user, each followed by the answer
+	ctx              context.Context
+	noStream         bool
+	pendingMessage   string // the last message of the user, which has not been answered
+	plainText        bool
+	reader           *bufio.Reader
+	systemPromptOpts *systemPromptOptions
+	temperature      float64
+}
+
+func Init_chat_Command(rootCmd *cobra.Command) {
+	var chatOpts chatOptions
+	var noStream bool
+	var shouldOutputAsPlainText bool
+	var systemPromptOpts systemPromptOptions
+	var temperature float64
+
+	chatCmd := &cobra.Command{
+		Use:   "chat",
+		Short: `Starts an interactive chat`,
+		Long: `Starts an interactive chat with ChatGPT or a similar API, which keeps the conversation in memory.
+
+Enter /help inside the chat for a list of commands.`,
+
+		Run: func(cmd *cobra.Command, args []string) {
+			repl := &chatRepl{
+				chatOpts:         &chatOpts,
+				ctx:              cmd.Context(),
+				noStream:         noStream,
+				plainText:        shouldOutputAsPlainText,
+				reader:           bufio.NewReader(os.Stdin),
+				systemPromptOpts: &systemPromptOpts,
+				temperature:      temperature,
+			}
+
+			err := repl.run()
+			if err != nil {
+				panic(err)
+			}
+		},
+	}
+
+	addSystemPromptFlags(chatCmd, &systemPromptOpts)
+	chatCmd.Flags().BoolVarP(&shouldOutputAsPlainText, "plain-text", "", false, "Output as plain text")
+	chatCmd.Flags().BoolVarP(&shouldOutputAsPlainText, "pt", "", false, "Output as plain text")
+	chatCmd.Flags().Float64VarP(&temperature, "temperature", "t", getDefaultTemperature(), "Custom temperature between 0 and 2")
+	chatCmd.Flags().BoolVarP(&noStream, "no-stream", "", false, "Wait for the complete answer before output")
+	chatCmd.Flags().BoolVarP(&noStream, "ns", "", false, "Wait for the complete answer before output")
+	addChatOptionFlags(chatCmd, &chatOpts)
+
+	rootCmd.AddCommand(chatCmd)
+}
+
+// run reads and handles the input of the user, until the end of STDIN
+// or `/exit` is reached
+func (repl *chatRepl) run() error {
+	fmt.Println(`Enter your message, /help for a list of commands or /exit to quit. Use """ to start and end multi-line messages.`)
+
+	for {
+		input, err := repl.readInput()
+		if err == io.EOF {
+			if strings.TrimSpace(input) == "" {
+				fmt.Println()
+				return nil
+			}
+		} else if err != nil {
+			return err
+		}
+
+		input = strings.TrimSpace(input)
+		if input == "" {
+			continue
+		}
+
+		if strings.HasPrefix(input, "/") {
+			shouldExit, err := repl.handleCommand(input)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err.Error())
+			}
+			if shouldExit {
+				return nil
+			}
+
+			continue
+		}
+
+		repl.sendMessage(repl.conversation, input)
+	}
+}
+
+// handleCommand executes the slash command in `input` and returns `true`,
+// if the chat should be closed
+func (repl *chatRepl) handleCommand(input string) (bool, error) {
+	command, arg, _ := strings.Cut(input, " ")
+	arg = strings.TrimSpace(arg)
+
+	switch strings.ToLower(command) {
+	case "/exit", "/quit":
+		return true, nil
+
+	case "/help":
+		fmt.Println(`/exit                  closes the chat
+/help                  shows this help
+/load <file>           loads a conversation, which has been saved with /save
+/reset                 starts a new conversation
+/retry                 sends the last message again, if it failed, or asks for a new answer
+/save <file>           saves the current conversation
+/system [prompt]       shows or sets the custom system prompt, "default" restores the default
+/temperature [value]   shows or sets the temperature between 0 and 2`)
+
+	case "/load":
+		if arg == "" {
+			return false, errors.New("no file defined")
+		}
+
+		data, err := os.ReadFile(arg)
+		if err != nil {
+			return false, err
+		}
+
+		var conversation chatConversation
+		err = json.Unmarshal(data, &conversation)
+		if err != nil {
+			return false, err
+		}
+
+		repl.conversation = conversation.Conversation
+		repl.pendingMessage = ""
+		if len(repl.conversation)%2 == 1 {
+			// last message has not been answered
+			repl.pendingMessage = repl.conversation[len(repl.conversation)-1]
+			repl.conversation = repl.conversation[:len(repl.conversation)-1]
+		}
+		repl.systemPromptOpts.system = conversation.System
+		if conversation.Temperature != nil {
+			repl.temperature = *conversation.Temperature
+		}
+
+		fmt.Printf("Loaded %v message(s) from %v\n", len(repl.conversation), arg)
+
+	case "/reset":
+		repl.conversation = nil
+		repl.pendingMessage = ""
+
+		fmt.Println("Started new conversation")
+
+	case "/retry":
+		if repl.pendingMessage != "" {
+			repl.sendMessage(repl.conversation, repl.pendingMessage)
+		} else if len(repl.conversation) >= 2 {
+			// replace the last answer, if the new one is received
+			lastIndex := len(repl.conversation) - 2
+			repl.sendMessage(repl.conversation[:lastIndex], repl.conversation[lastIndex])
+		} else {
+			return false, errors.New("nothing to retry")
+		}
+
+	case "/save":
+		if arg == "" {
+			return false, errors.New("no file defined")
+		}
+
+		temperature := repl.temperature
+		data, err := json.MarshalIndent(chatConversation{
+			Conversation: repl.conversation,
+			System:       repl.systemPromptOpts.system,
+			Temperature:  &temperature,
+		}, "", "  ")
+		if err != nil {
+			return false, err
+		}
+
+		err = os.WriteFile(arg, data, 0600)
+		if err != nil {
+			return false, err
+		}
+
+		fmt.Printf("Saved %v message(s) to %v\n", len(repl.conversation), arg)
+
+	case "/system":
+		if arg == "" {
+			systemPrompt, err := repl.systemPromptOpts.build()
+			if err != nil {
+				return false, err
+			}
+
+			fmt.Println(systemPrompt)
+		} else if strings.ToLower(arg) == "default" {
+			repl.systemPromptOpts.system = ""
+
+			fmt.Println("Restored default system prompt")
+		} else {
+			repl.systemPromptOpts.system = arg
+
+			fmt.Println("Updated system prompt")
+		}
+
+	case "/temperature":
+		if arg == "" {
+			fmt.Println(repl.temperature)
+		} else {
+			temperature, err := strconv.ParseFloat(arg, 64)
+			if err != nil || temperature < 0 || temperature > 2 {
+				return false, errors.New("temperature must be a number between 0 and 2")
+			}
+
+			repl.temperature = temperature
+
+			fmt.Printf("Updated temperature to %v\n", temperature)
+		}
+
+	default:
+		return false, fmt.Errorf("unknown command %v, enter /help for a list of commands", command)
+	}
+
+	return false, nil
+}
+
+// readInput reads the next message from STDIN, which can span multiple lines,
+// if it is enclosed in """ or if lines end with a backslash
+func (repl *chatRepl) readInput() (string, error) {
+	var lines []string
+
+	isMultiLine := false
+	prompt := "> "
+
+	for {
+		egoUtils.WriteStringToStdOut(prompt, false)
+
+		line, err := repl.reader.ReadString('\n')
+		line = strings.TrimRight(line, "\r\n")
+
+		if err != nil {
+			lines = append(lines, line)
+			return strings.Join(lines, "\n"), err
+		}
+
+		prompt = ". "
+
+		if isMultiLine {
+			if strings.TrimSpace(line) == chatMultiLineDelimiter {
+				return strings.Join(lines, "\n"), nil
+			}
+
+			lines = append(lines, line)
+			continue
+		}
+
+		if len(lines) == 0 && strings.TrimSpace(line) == chatMultiLineDelimiter {
+			isMultiLine = true
+			continue
+		}
+
+		if strings.HasSuffix(line, "\\") {
+			lines = append(lines, strings.TrimSuffix(line, "\\"))
+			continue
+		}
+
+		lines = append(lines, line)
+
+		return strings.Join(lines, "\n"), nil
+	}
+}
+
+// sendMessage sends `message` of the user with the previous messages of
+// `conversation` and makes both the new conversation with the answer. If
+// no answer is received, `message` is kept for `/retry`. Pressing Ctrl+C only
+// aborts the current answer.
+func (repl *chatRepl) sendMessage(conversation []string, message string) {
+	repl.pendingMessage = message
+
+	systemPrompt, err := repl.systemPromptOpts.build()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return
+	}
+
+	ctx, stop := withInterrupt(repl.ctx)
+	defer stop()
+
+	messages := append(append([]string{}, conversation...), message)
+
+	answer, err := writeAnswerStream(
+		ctx,
+		repl.chatOpts.newRequest(systemPrompt, repl.temperature, messages...),
+		outputAnswerOptions{
+			NoStream:  repl.noStream,
+			PlainText: repl.plainText,
+		},
+	)
+	if err != nil {
+		if ctx.Err() != nil && repl.ctx.Err() == nil {
+			fmt.Fprintln(os.Stderr)
+			fmt.Fprintln(os.Stderr, "Aborted")
+		} else {
+			fmt.Fprintln(os.Stderr, err.Error())
+		}
+
+		return
+	}
+
+	repl.conversation = append(messages, answer)
+	repl.pendingMessage = ""
+}
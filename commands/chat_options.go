@@ -0,0 +1,60 @@
+// This file is part of the e.GPT distribution.
+// Copyright (c) Next.e.GO Mobile SE, Aachen, Germany (https://e-go-mobile.com/)
+//
+// e-gpt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, version 3.
+//
+// e-gpt is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package commands
+
+import (
+	"github.com/spf13/cobra"
+
+	egoOpenAI "github.com/egomobile/e-gpt/openai"
+)
+
+// chatOptions contains the values of flags, which are shared by all commands using a chat API
+type chatOptions struct {
+	frequencyPenalty float64
+	maxTokens        int64
+	model            string
+	noCache          bool
+	presencePenalty  float64
+	stop             []string
+	topP             float64
+}
+
+// addChatOptionFlags adds the flags for `options` to `cmd`
+func addChatOptionFlags(cmd *cobra.Command, options *chatOptions) {
+	cmd.Flags().Float64VarP(&options.frequencyPenalty, "frequency-penalty", "", 0, "Custom frequency penalty between -2 and 2")
+	cmd.Flags().Int64VarP(&options.maxTokens, "max-tokens", "", 0, "Maximum number of tokens to generate")
+	cmd.Flags().StringVarP(&options.model, "model", "m", "", "Custom model, like gpt-4")
+	cmd.Flags().BoolVarP(&options.noCache, "no-cache", "", false, "Do not use the response cache")
+	cmd.Flags().Float64VarP(&options.presencePenalty, "presence-penalty", "", 0, "Custom presence penalty between -2 and 2")
+	cmd.Flags().StringArrayVarP(&options.stop, "stop", "", []string{}, "Sequence at which to stop generation")
+	cmd.Flags().Float64VarP(&options.topP, "top-p", "", 0, "Custom top-p sampling cutoff between 0 and 1")
+}
+
+// newRequest creates a new request for the chat API from `options`
+func (options *chatOptions) newRequest(systemPrompt string, temperature float64, conversation ...string) egoOpenAI.CompletionRequest {
+	return egoOpenAI.CompletionRequest{
+		FrequencyPenalty: options.frequencyPenalty,
+		MaxTokens:        options.maxTokens,
+		Messages:         egoOpenAI.ConversationToMessages(conversation...),
+		Model:            options.model,
+		NoCache:          options.noCache,
+		PresencePenalty:  options.presencePenalty,
+		Stop:             options.stop,
+		SystemPrompt:     systemPrompt,
+		Temperature:      temperature,
+		TopP:             options.topP,
+	}
+}
@@ -22,11 +22,11 @@ import (
 
 	"github.com/spf13/cobra"
 
-	egoOpenAI "github.com/egomobile/e-gpt/openai"
 	egoUtils "github.com/egomobile/e-gpt/utils"
 )
 
 func Init_code_Command(rootCmd *cobra.Command) {
+	var chatOpts chatOptions
 	var noNewLine bool = egoUtils.GetDefaultAddNoNewLineToChatAnswerSetting()
 	var openEditor bool
 	var temperature float64
@@ -55,16 +55,15 @@ If possible, you will never use external dependencies like external libraries or
 If the user does not specify a programming language you have to use TypeScript for the output.`,
 			)
 
-			answer, err := egoOpenAI.AskChatGPT(
-				strings.TrimSpace(systemPrompt.String()),
-				temperature,
-				question,
+			response, err := completeAnswer(
+				cmd.Context(),
+				chatOpts.newRequest(strings.TrimSpace(systemPrompt.String()), temperature, question),
 			)
 			if err != nil {
 				log.Fatalln(err.Error())
 			}
 
-			answer = egoUtils.RemoveMarkdownCode(answer)
+			answer := egoUtils.RemoveMarkdownCode(response.Answer)
 
 			egoUtils.WriteStringToStdOut(answer, !noNewLine)
 		},
@@ -74,6 +73,7 @@ If the user does not specify a programming language you have to use TypeScript f
 	codeCmd.Flags().Float64VarP(&temperature, "temperature", "t", getDefaultTemperature(), "Custom temperature between 0 and 2")
 	codeCmd.Flags().BoolVarP(&noNewLine, "no-new-line", "", egoUtils.GetDefaultAddNoNewLineToChatAnswerSetting(), "Do not add new line at the end")
 	codeCmd.Flags().BoolVarP(&noNewLine, "nnl", "", egoUtils.GetDefaultAddNoNewLineToChatAnswerSetting(), "Do not add new line at the end")
+	addChatOptionFlags(codeCmd, &chatOpts)
 
 	rootCmd.AddCommand(codeCmd)
 }
@@ -4,19 +4,18 @@ import (
 	"bytes"
 	"fmt"
 	"log"
-	"os"
 	"strings"
 	"time"
 
-	"github.com/alecthomas/chroma/quick"
 	"github.com/spf13/cobra"
 
-	egoOpenAI "github.com/egomobile/e-gpt/openai"
 	egoUtils "github.com/egomobile/e-gpt/utils"
 )
 
 func Init_describe_Command(rootCmd *cobra.Command) {
+	var chatOpts chatOptions
 	var noNewLine bool = egoUtils.GetDefaultAddNoNewLineToChatAnswerSetting()
+	var noStream bool
 	var openEditor bool
 	var temperature float64
 
@@ -77,19 +76,18 @@ If you need to store any data, assume it will be stored in the chat.
 				systemPrompt.WriteString(fmt.Sprintln())
 			}
 
-			answer, err := egoOpenAI.AskChatGPT(
-				strings.TrimSpace(systemPrompt.String()),
-				temperature,
-				question,
+			_, err := outputAnswer(
+				cmd.Context(),
+				chatOpts.newRequest(strings.TrimSpace(systemPrompt.String()), temperature, question),
+				outputAnswerOptions{
+					Lexer:     "markdown",
+					NoNewLine: noNewLine,
+					NoStream:  noStream,
+				},
 			)
 			if err != nil {
 				log.Fatalln(err.Error())
 			}
-
-			err = quick.Highlight(os.Stdout, answer, "markdown", "", "monokai")
-			if err != nil {
-				egoUtils.WriteStringToStdOut(answer, !noNewLine)
-			}
 		},
 	}
 
@@ -97,6 +95,9 @@ If you need to store any data, assume it will be stored in the chat.
 	describeCmd.Flags().Float64VarP(&temperature, "temperature", "t", getDefaultTemperature(), "Custom temperature between 0 and 2")
 	describeCmd.Flags().BoolVarP(&noNewLine, "no-new-line", "", egoUtils.GetDefaultAddNoNewLineToChatAnswerSetting(), "Do not add new line at the end")
 	describeCmd.Flags().BoolVarP(&noNewLine, "nnl", "", egoUtils.GetDefaultAddNoNewLineToChatAnswerSetting(), "Do not add new line at the end")
+	describeCmd.Flags().BoolVarP(&noStream, "no-stream", "", false, "Wait for the complete answer before output")
+	describeCmd.Flags().BoolVarP(&noStream, "ns", "", false, "Wait for the complete answer before output")
+	addChatOptionFlags(describeCmd, &chatOpts)
 
 	rootCmd.AddCommand(describeCmd)
 }
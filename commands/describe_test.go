@@ -0,0 +1,70 @@
+// This file is part of the e.GPT distribution.
+// Copyright (c) Next.e.GO Mobile SE, Aachen, Germany (https://e-go-mobile.com/)
+//
+// e-gpt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, version 3.
+//
+// e-gpt is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package commands
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestDescribeUsesResponseCache(t *testing.T) {
+	requestCount := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestCount++
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"Lists files."}}]}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("CHAT_API_PROVIDER", "openai")
+	t.Setenv("CHAT_CACHE", "true")
+	t.Setenv("CHAT_USAGE_LEDGER", "false")
+	t.Setenv("OPENAI_API_KEY", "test")
+	t.Setenv("OPENAI_BASE_URL", server.URL)
+
+	stdin, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdin.Close()
+
+	oldStdin := os.Stdin
+	os.Stdin = stdin
+	defer func() {
+		os.Stdin = oldStdin
+	}()
+
+	for i := 0; i < 2; i++ {
+		rootCmd := &cobra.Command{Use: "egpt"}
+		Init_describe_Command(rootCmd)
+
+		rootCmd.SetArgs([]string{"describe", "--no-stream", "ls -la"})
+		err := rootCmd.Execute()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if requestCount != 1 {
+		t.Errorf("expected 1 API call, got %v", requestCount)
+	}
+}
@@ -19,19 +19,18 @@ import (
 	"bytes"
 	"fmt"
 	"log"
-	"os"
 	"strings"
 
-	"github.com/alecthomas/chroma/quick"
 	"github.com/spf13/cobra"
 
-	egoOpenAI "github.com/egomobile/e-gpt/openai"
 	egoUtils "github.com/egomobile/e-gpt/utils"
 )
 
 func Init_explain_Command(rootCmd *cobra.Command) {
+	var chatOpts chatOptions
 	var language string
 	var noNewLine bool = egoUtils.GetDefaultAddNoNewLineToChatAnswerSetting()
+	var noStream bool
 	var openEditor bool
 	var temperature float64
 
@@ -58,23 +57,18 @@ Ignore any potential risk of errors or confusion`,
 					programmingLanguage, programmingLanguage),
 			)
 
-			answer, err := egoOpenAI.AskChatGPT(
-				strings.TrimSpace(systemPrompt.String()),
-				temperature,
-				question,
+			_, err := outputAnswer(
+				cmd.Context(),
+				chatOpts.newRequest(strings.TrimSpace(systemPrompt.String()), temperature, question),
+				outputAnswerOptions{
+					Lexer:     "markdown",
+					NoNewLine: noNewLine,
+					NoStream:  noStream,
+				},
 			)
 			if err != nil {
 				log.Fatalln(err.Error())
 			}
-
-			outputPlain := func() {
-				egoUtils.WriteStringToStdOut(answer, !noNewLine)
-			}
-
-			err = quick.Highlight(os.Stdout, answer, "markdown", "", "monokai")
-			if err != nil {
-				outputPlain()
-			}
 		},
 	}
 
@@ -83,6 +77,9 @@ Ignore any potential risk of errors or confusion`,
 	explainCmd.Flags().Float64VarP(&temperature, "temperature", "t", getDefaultTemperature(), "Custom temperature between 0 and 2")
 	explainCmd.Flags().BoolVarP(&noNewLine, "no-new-line", "", egoUtils.GetDefaultAddNoNewLineToChatAnswerSetting(), "Do not add new line at the end")
 	explainCmd.Flags().BoolVarP(&noNewLine, "nnl", "", egoUtils.GetDefaultAddNoNewLineToChatAnswerSetting(), "Do not add new line at the end")
+	explainCmd.Flags().BoolVarP(&noStream, "no-stream", "", false, "Wait for the complete answer before output")
+	explainCmd.Flags().BoolVarP(&noStream, "ns", "", false, "Wait for the complete answer before output")
+	addChatOptionFlags(explainCmd, &chatOpts)
 
 	rootCmd.AddCommand(explainCmd)
 }
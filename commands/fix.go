@@ -19,20 +19,19 @@ import (
 	"bytes"
 	"fmt"
 	"log"
-	"os"
 	"strings"
 
-	"github.com/alecthomas/chroma/quick"
 	"github.com/spf13/cobra"
 
-	egoOpenAI "github.com/egomobile/e-gpt/openai"
 	egoUtils "github.com/egomobile/e-gpt/utils"
 )
 
 func Init_fix_Command(rootCmd *cobra.Command) {
 	var additionalInfo string
+	var chatOpts chatOptions
 	var language string
 	var noNewLine bool = egoUtils.GetDefaultAddNoNewLineToChatAnswerSetting()
+	var noStream bool
 	var openEditor bool
 	var temperature float64
 
@@ -78,23 +77,18 @@ func Init_fix_Command(rootCmd *cobra.Command) {
 				)
 			}
 
-			answer, err := egoOpenAI.AskChatGPT(
-				strings.TrimSpace(systemPrompt.String()),
-				temperature,
-				text,
+			_, err := outputAnswer(
+				cmd.Context(),
+				chatOpts.newRequest(strings.TrimSpace(systemPrompt.String()), temperature, text),
+				outputAnswerOptions{
+					Lexer:     "markdown",
+					NoNewLine: noNewLine,
+					NoStream:  noStream,
+				},
 			)
 			if err != nil {
 				log.Fatalln(err.Error())
 			}
-
-			outputPlain := func() {
-				egoUtils.WriteStringToStdOut(answer, !noNewLine)
-			}
-
-			err = quick.Highlight(os.Stdout, answer, "markdown", "", "monokai")
-			if err != nil {
-				outputPlain()
-			}
 		},
 	}
 
@@ -104,6 +98,9 @@ func Init_fix_Command(rootCmd *cobra.Command) {
 	translateCmd.Flags().Float64VarP(&temperature, "temperature", "t", getDefaultTemperature(), "Custom temperature between 0 and 2")
 	translateCmd.Flags().BoolVarP(&noNewLine, "no-new-line", "", egoUtils.GetDefaultAddNoNewLineToChatAnswerSetting(), "Do not add new line at the end")
 	translateCmd.Flags().BoolVarP(&noNewLine, "nnl", "", egoUtils.GetDefaultAddNoNewLineToChatAnswerSetting(), "Do not add new line at the end")
+	translateCmd.Flags().BoolVarP(&noStream, "no-stream", "", false, "Wait for the complete answer before output")
+	translateCmd.Flags().BoolVarP(&noStream, "ns", "", false, "Wait for the complete answer before output")
+	addChatOptionFlags(translateCmd, &chatOpts)
 
 	rootCmd.AddCommand(translateCmd)
 }
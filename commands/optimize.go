@@ -19,19 +19,18 @@ import (
 	"bytes"
 	"fmt"
 	"log"
-	"os"
 	"strings"
 
-	"github.com/alecthomas/chroma/quick"
 	"github.com/spf13/cobra"
 
-	egoOpenAI "github.com/egomobile/e-gpt/openai"
 	egoUtils "github.com/egomobile/e-gpt/utils"
 )
 
 func Init_optimize_Command(rootCmd *cobra.Command) {
+	var chatOpts chatOptions
 	var language string
 	var noNewLine bool = egoUtils.GetDefaultAddNoNewLineToChatAnswerSetting()
+	var noStream bool
 	var openEditor bool
 	var temperature float64
 
@@ -66,23 +65,18 @@ Ignore any potential risk of errors or confusion.%v`, "\n"),
 				)
 			}
 
-			answer, err := egoOpenAI.AskChatGPT(
-				strings.TrimSpace(systemPrompt.String()),
-				temperature,
-				question,
+			_, err := outputAnswer(
+				cmd.Context(),
+				chatOpts.newRequest(strings.TrimSpace(systemPrompt.String()), temperature, question),
+				outputAnswerOptions{
+					Lexer:     "markdown",
+					NoNewLine: noNewLine,
+					NoStream:  noStream,
+				},
 			)
 			if err != nil {
 				log.Fatalln(err.Error())
 			}
-
-			outputPlain := func() {
-				egoUtils.WriteStringToStdOut(answer, !noNewLine)
-			}
-
-			err = quick.Highlight(os.Stdout, answer, "markdown", "", "monokai")
-			if err != nil {
-				outputPlain()
-			}
 		},
 	}
 
@@ -91,6 +85,9 @@ Ignore any potential risk of errors or confusion.%v`, "\n"),
 	optimizeCmd.Flags().Float64VarP(&temperature, "temperature", "t", getDefaultTemperature(), "Custom temperature between 0 and 2")
 	optimizeCmd.Flags().BoolVarP(&noNewLine, "no-new-line", "", egoUtils.GetDefaultAddNoNewLineToChatAnswerSetting(), "Do not add new line at the end")
 	optimizeCmd.Flags().BoolVarP(&noNewLine, "nnl", "", egoUtils.GetDefaultAddNoNewLineToChatAnswerSetting(), "Do not add new line at the end")
+	optimizeCmd.Flags().BoolVarP(&noStream, "no-stream", "", false, "Wait for the complete answer before output")
+	optimizeCmd.Flags().BoolVarP(&noStream, "ns", "", false, "Wait for the complete answer before output")
+	addChatOptionFlags(optimizeCmd, &chatOpts)
 
 	rootCmd.AddCommand(optimizeCmd)
 }
@@ -0,0 +1,178 @@
+// This file is part of the e.GPT distribution.
+// Copyright (c) Next.e.GO Mobile SE, Aachen, Germany (https://e-go-mobile.com/)
+//
+// e-gpt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, version 3.
+//
+// e-gpt is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+
+	egoUtils "github.com/egomobile/e-gpt/utils"
+)
+
+func Init_session_Command(rootCmd *cobra.Command) {
+	var exportFormat string
+	var exportOutput string
+
+	sessionCmd := &cobra.Command{
+		Use:     "session",
+		Short:   `Handle chat sessions`,
+		Long:    `Lists, shows, exports or deletes chat sessions, which are created by ask --session`,
+		Aliases: []string{"sessions"},
+	}
+
+	listCmd := &cobra.Command{
+		Use:     "list",
+		Short:   `List sessions`,
+		Long:    `Lists all stored chat sessions`,
+		Aliases: []string{"ls"},
+
+		Run: func(cmd *cobra.Command, args []string) {
+			sessions, err := egoUtils.ListChatSessions()
+			if err != nil {
+				panic(err)
+			}
+
+			t := table.NewWriter()
+			t.SetOutputMirror(os.Stdout)
+			t.AppendHeader(table.Row{"Name", "Messages", "Created", "Updated"})
+
+			for _, session := range sessions {
+				t.AppendRow(table.Row{
+					session.Name,
+					len(session.Conversation),
+					session.CreatedAt.Local().Format(time.RFC3339),
+					session.UpdatedAt.Local().Format(time.RFC3339),
+				})
+			}
+
+			t.Render()
+		},
+	}
+
+	showCmd := &cobra.Command{
+		Use:   "show <name>",
+		Short: `Show session`,
+		Long:  `Shows the conversation of a chat session`,
+		Args:  cobra.ExactArgs(1),
+
+		Run: func(cmd *cobra.Command, args []string) {
+			session := loadExistingChatSession(args[0])
+
+			os.Stdout.WriteString(sessionToMarkdown(session))
+		},
+	}
+
+	exportCmd := &cobra.Command{
+		Use:   "export <name>",
+		Short: `Export session`,
+		Long:  `Exports a chat session as Markdown or JSON`,
+		Args:  cobra.ExactArgs(1),
+
+		Run: func(cmd *cobra.Command, args []string) {
+			session := loadExistingChatSession(args[0])
+
+			var data []byte
+			switch strings.TrimSpace(strings.ToLower(exportFormat)) {
+			case "", "md", "markdown":
+				data = []byte(sessionToMarkdown(session))
+			case "json":
+				jsonData, err := json.MarshalIndent(session, "", "  ")
+				if err != nil {
+					panic(err)
+				}
+
+				data = append(jsonData, '\n')
+			default:
+				panic(fmt.Errorf("format %v not supported", exportFormat))
+			}
+
+			output := strings.TrimSpace(exportOutput)
+			if output == "" {
+				egoUtils.WriteToStdOut(data, false)
+				return
+			}
+
+			err := os.WriteFile(output, data, 0600)
+			if err != nil {
+				panic(err)
+			}
+		},
+	}
+
+	deleteCmd := &cobra.Command{
+		Use:     "delete <name>",
+		Short:   `Delete session`,
+		Long:    `Deletes a chat session`,
+		Aliases: []string{"rm"},
+		Args:    cobra.ExactArgs(1),
+
+		Run: func(cmd *cobra.Command, args []string) {
+			err := egoUtils.DeleteChatSession(args[0])
+			if err != nil {
+				panic(err)
+			}
+		},
+	}
+
+	exportCmd.Flags().StringVarP(&exportFormat, "format", "f", "markdown", "Output format, markdown or json")
+	exportCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "Write to file instead of STDOUT")
+
+	sessionCmd.AddCommand(listCmd)
+	sessionCmd.AddCommand(showCmd)
+	sessionCmd.AddCommand(exportCmd)
+	sessionCmd.AddCommand(deleteCmd)
+
+	rootCmd.AddCommand(sessionCmd)
+}
+
+// loadExistingChatSession loads the session `name` and panics, if it does not exist
+func loadExistingChatSession(name string) egoUtils.ChatSession {
+	session, err := egoUtils.LoadChatSession(name)
+	if err != nil {
+		panic(err)
+	}
+
+	if len(session.Conversation) == 0 {
+		panic(fmt.Errorf("session %v not found", name))
+	}
+
+	return session
+}
+
+// sessionToMarkdown converts the conversation of `session` to Markdown
+func sessionToMarkdown(session egoUtils.ChatSession) string {
+	var markdown bytes.Buffer
+
+	markdown.WriteString(fmt.Sprintf("# %v\n", session.Name))
+
+	for i, message := range session.Conversation {
+		role := "User"
+		if i%2 == 1 {
+			role = "Assistant"
+		}
+
+		markdown.WriteString(fmt.Sprintf("\n## %v\n\n%v\n", role, strings.TrimSpace(message)))
+	}
+
+	return markdown.String()
+}
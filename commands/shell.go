@@ -12,11 +12,11 @@ import (
 
 	"github.com/spf13/cobra"
 
-	egoOpenAI "github.com/egomobile/e-gpt/openai"
 	egoUtils "github.com/egomobile/e-gpt/utils"
 )
 
 func Init_shell_Command(rootCmd *cobra.Command) {
+	var chatOpts chatOptions
 	var openEditor bool
 	var temperature float64
 	var withExitCode bool
@@ -78,15 +78,16 @@ If multiple steps required try to combine them together.
 				systemPrompt.WriteString(fmt.Sprintln())
 			}
 
-			answer, err := egoOpenAI.AskChatGPT(
-				strings.TrimSpace(systemPrompt.String()),
-				temperature,
-				question,
+			response, err := completeAnswer(
+				cmd.Context(),
+				chatOpts.newRequest(strings.TrimSpace(systemPrompt.String()), temperature, question),
 			)
 			if err != nil {
 				log.Fatalln(err.Error())
 			}
 
+			answer := response.Answer
+
 			os.Stdout.WriteString(fmt.Sprintln(answer))
 			os.Stdout.WriteString("[E]xecute, [a]bort ")
 
@@ -131,6 +132,7 @@ If multiple steps required try to combine them together.
 	shellCmd.Flags().BoolVarP(&withExitCode, "exit-code", "", false, "Also return exit code from execution")
 	shellCmd.Flags().BoolVarP(&withExitCode, "ec", "", false, "Also return exit code from execution")
 	shellCmd.Flags().Float64VarP(&temperature, "temperature", "t", getDefaultTemperature(), "Custom temperature between 0 and 2")
+	addChatOptionFlags(shellCmd, &chatOpts)
 
 	rootCmd.AddCommand(shellCmd)
 }
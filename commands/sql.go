@@ -4,7 +4,6 @@ import (
 	"bufio"
 	"bytes"
 	"database/sql"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
@@ -14,52 +13,89 @@ import (
 	"time"
 
 	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/lib/pq"
 	"github.com/spf13/cobra"
 
-	egoOpenAI "github.com/egomobile/e-gpt/openai"
 	egoUtils "github.com/egomobile/e-gpt/utils"
 )
 
-type GetTablesFunc func() ([]string, error)
+// GetTablesFunc returns the structure of all tables of a database.
+type GetTablesFunc func() ([]TableStructure, error)
+
+// postgresColumn is a column of a table or view of PostgreSQL
+type postgresColumn struct {
+	Comment         sql.NullString // the comment of the column
+	DataType        string         // the data type, like `character varying(255)`
+	IsNotNull       bool           // the column is NOT NULL
+	Name            string         // the name of the column
+	Relation        string         // the name of the table or view
+	RelationComment sql.NullString // the comment of the table or view
+	RelationKind    string         // `r` or `p` for tables, `v` for views and `m` for materialized views
+	Schema          string         // the schema of the table or view
+	ViewQuery       sql.NullString // the query of a view
+}
+
+// postgresConstraint is a PRIMARY KEY, UNIQUE or FOREIGN KEY constraint of PostgreSQL
+type postgresConstraint struct {
+	Definition string // the definition, like `PRIMARY KEY (id)`
+	Name       string // the name of the constraint
+	Schema     string // the schema of the table
+	Table      string // the name of the table
+}
+
+// postgresEnumLabel is a label of an enum type of PostgreSQL
+type postgresEnumLabel struct {
+	Label  string // the label
+	Schema string // the schema of the type
+	Type   string // the name of the type
+}
 
-func getPostgresTables(db *sql.DB) ([]string, error) {
-	query := `SELECT t.table_name, c.column_name::text, c.data_type, c.is_nullable
-FROM information_schema.tables t
-INNER JOIN information_schema.columns c ON t.table_name = c.table_name
-WHERE t.table_schema = 'public' AND t.table_type= 'BASE TABLE' AND c.table_schema = 'public';`
+func getMySQLTables(db *sql.DB) ([]TableStructure, error) {
+	query := `SELECT c.table_name, c.column_name, c.column_type, c.is_nullable, c.column_key
+FROM information_schema.columns c
+INNER JOIN information_schema.tables t ON t.table_schema = c.table_schema AND t.table_name = c.table_name
+WHERE c.table_schema = DATABASE() AND t.table_type = 'BASE TABLE'
+ORDER BY c.table_name, c.ordinal_position;`
 
 	rows, err := db.Query(query)
 	if err != nil {
-		return []string{}, err
+		return []TableStructure{}, err
 	}
 
 	defer rows.Close()
 
+	var tableNames []string
 	allTablesAndColumns := make(map[string][]string)
+	allPrimaryKeys := make(map[string][]string)
 
 	for rows.Next() {
 		var tableName string
 		var columnName string
 		var dataType string
 		var isNullable string
+		var columnKey string
 
-		err := rows.Scan(&tableName, &columnName, &dataType, &isNullable)
+		err := rows.Scan(&tableName, &columnName, &dataType, &isNullable, &columnKey)
 		if err != nil {
-			return []string{}, err
+			return []TableStructure{}, err
 		}
 
 		tableCols, ok := allTablesAndColumns[tableName]
 		if !ok {
-			allTablesAndColumns[tableName] = []string{}
+			tableNames = append(tableNames, tableName)
 		}
 
 		notNull := ""
-		if isNullable == "YES" {
+		if isNullable == "NO" {
 			notNull = "NOT NULL"
 		}
 
+		if columnKey == "PRI" {
+			allPrimaryKeys[tableName] = append(allPrimaryKeys[tableName], fmt.Sprintf("`%v`", columnName))
+		}
+
 		stmt := fmt.Sprintf(
-			`"%v" %v %v`,
+			"`%v` %v %v",
 			columnName, dataType, notNull,
 		)
 
@@ -69,28 +105,689 @@ WHERE t.table_schema = 'public' AND t.table_type= 'BASE TABLE' AND c.table_schem
 		)
 	}
 
-	var createStmts []string
+	err = rows.Err()
+	if err != nil {
+		return []TableStructure{}, err
+	}
+
+	var createStmts []TableStructure
+
+	for _, tableName := range tableNames {
+		tableCols := allTablesAndColumns[tableName]
+
+		primaryKeys := allPrimaryKeys[tableName]
+		if len(primaryKeys) > 0 {
+			tableCols = append(tableCols, fmt.Sprintf("PRIMARY KEY (%v)", strings.Join(primaryKeys, ",")))
+		}
 
-	for tableName, tableCols := range allTablesAndColumns {
 		stmt := fmt.Sprintf(
-			`CREATE TABLE public.%v (%v);`,
+			"CREATE TABLE `%v` (%v);",
 			tableName,
 			strings.Join(tableCols, ","),
 		)
 
-		createStmts = append(
-			createStmts,
+		createStmts = append(createStmts, TableStructure{
+			DDL:  strings.TrimSpace(stmt),
+			Name: tableName,
+			Type: "table",
+		})
+	}
+
+	return createStmts, nil
+}
+
+// getPostgresColumns returns the columns of all tables and views in `schemas`
+func getPostgresColumns(db *sql.DB, schemas []string) ([]postgresColumn, error) {
+	query := `SELECT n.nspname, cl.relname, cl.relkind, obj_description(cl.oid, 'pg_class'),
+CASE WHEN cl.relkind IN ('v', 'm') THEN pg_get_viewdef(cl.oid, true) END,
+a.attname, format_type(a.atttypid, a.atttypmod), a.attnotnull, col_description(cl.oid, a.attnum)
+FROM pg_catalog.pg_class cl
+INNER JOIN pg_catalog.pg_namespace n ON n.oid = cl.relnamespace
+INNER JOIN pg_catalog.pg_attribute a ON a.attrelid = cl.oid
+WHERE n.nspname = ANY($1) AND cl.relkind IN ('r', 'p', 'v', 'm') AND NOT cl.relispartition AND a.attnum > 0 AND NOT a.attisdropped
+ORDER BY n.nspname, cl.relname, a.attnum;`
+
+	rows, err := db.Query(query, pq.Array(schemas))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var columns []postgresColumn
+	for rows.Next() {
+		var column postgresColumn
+
+		err := rows.Scan(
+			&column.Schema, &column.Relation, &column.RelationKind, &column.RelationComment, &column.ViewQuery,
+			&column.Name, &column.DataType, &column.IsNotNull, &column.Comment,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		columns = append(columns, column)
+	}
+
+	return columns, rows.Err()
+}
+
+// getPostgresConstraints returns the PRIMARY KEY, UNIQUE and FOREIGN KEY
+// constraints of all tables in `schemas`
+func getPostgresConstraints(db *sql.DB, schemas []string) ([]postgresConstraint, error) {
+	query := `SELECT n.nspname, cl.relname, co.conname, pg_get_constraintdef(co.oid, true)
+FROM pg_catalog.pg_constraint co
+INNER JOIN pg_catalog.pg_class cl ON cl.oid = co.conrelid
+INNER JOIN pg_catalog.pg_namespace n ON n.oid = cl.relnamespace
+WHERE n.nspname = ANY($1) AND co.contype IN ('p', 'u', 'f')
+ORDER BY n.nspname, cl.relname, CASE co.contype WHEN 'p' THEN 0 WHEN 'u' THEN 1 ELSE 2 END, co.conname;`
+
+	rows, err := db.Query(query, pq.Array(schemas))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var constraints []postgresConstraint
+	for rows.Next() {
+		var constraint postgresConstraint
+
+		err := rows.Scan(&constraint.Schema, &constraint.Table, &constraint.Name, &constraint.Definition)
+		if err != nil {
+			return nil, err
+		}
+
+		constraints = append(constraints, constraint)
+	}
+
+	return constraints, rows.Err()
+}
+
+// getPostgresDDL creates a CREATE TYPE statement for each enum and the DDL of
+// all tables and views, including constraints and comments, from the
+// results of getPostgresColumns(), getPostgresConstraints() and getPostgresEnums()
+func getPostgresDDL(columns []postgresColumn, constraints []postgresConstraint, enumLabels []postgresEnumLabel) []TableStructure {
+	createStmts := getPostgresEnumDDL(enumLabels)
+
+	tableConstraints := make(map[string][]string)
+	for _, constraint := range constraints {
+		fullTableName := toPostgresName(constraint.Schema, constraint.Table)
+
+		tableConstraints[fullTableName] = append(
+			tableConstraints[fullTableName],
+			fmt.Sprintf("CONSTRAINT %v %v", toPostgresName(constraint.Name), constraint.Definition),
+		)
+	}
+
+	type postgresRelation struct {
+		columnNames []string
+		columns     []string
+		comments    []string
+		kind        string
+		name        string
+		schema      string
+		viewQuery   string
+	}
+
+	var relationNames []string
+	allRelations := make(map[string]*postgresRelation)
+
+	for _, column := range columns {
+		fullRelationName := toPostgresName(column.Schema, column.Relation)
+
+		relation, ok := allRelations[fullRelationName]
+		if !ok {
+			relation = &postgresRelation{
+				kind:      column.RelationKind,
+				name:      column.Relation,
+				schema:    column.Schema,
+				viewQuery: strings.TrimSuffix(strings.TrimSpace(column.ViewQuery.String), ";"),
+			}
+
+			if column.RelationComment.Valid {
+				objectType := "TABLE"
+				if column.RelationKind == "v" {
+					objectType = "VIEW"
+				} else if column.RelationKind == "m" {
+					objectType = "MATERIALIZED VIEW"
+				}
+
+				relation.comments = append(
+					relation.comments,
+					fmt.Sprintf("COMMENT ON %v %v IS %v;", objectType, fullRelationName, toPostgresString(column.RelationComment.String)),
+				)
+			}
+
+			allRelations[fullRelationName] = relation
+			relationNames = append(relationNames, fullRelationName)
+		}
+
+		notNull := ""
+		if column.IsNotNull {
+			notNull = "NOT NULL"
+		}
+
+		stmt := fmt.Sprintf(
+			`%v %v %v`,
+			toPostgresName(column.Name), column.DataType, notNull,
+		)
+
+		relation.columnNames = append(relation.columnNames, toPostgresName(column.Name))
+		relation.columns = append(
+			relation.columns,
+			strings.TrimSpace(stmt),
+		)
+
+		if column.Comment.Valid {
+			relation.comments = append(
+				relation.comments,
+				fmt.Sprintf(
+					"COMMENT ON COLUMN %v.%v IS %v;",
+					fullRelationName, toPostgresName(column.Name), toPostgresString(column.Comment.String),
+				),
+			)
+		}
+	}
+
+	for _, relationName := range relationNames {
+		relation := allRelations[relationName]
+
+		var stmt string
+		relationType := "view"
+		switch relation.kind {
+		case "m":
+			stmt = fmt.Sprintf(
+				"CREATE MATERIALIZED VIEW %v (%v) AS %v;",
+				relationName, strings.Join(relation.columnNames, ","), relation.viewQuery,
+			)
+		case "v":
+			stmt = fmt.Sprintf(
+				"CREATE VIEW %v (%v) AS %v;",
+				relationName, strings.Join(relation.columnNames, ","), relation.viewQuery,
+			)
+		default:
+			relationType = "table"
+			tableCols := append(relation.columns, tableConstraints[relationName]...)
+
+			stmt = fmt.Sprintf(
+				`CREATE TABLE %v (%v);`,
+				relationName,
+				strings.Join(tableCols, ","),
+			)
+		}
+
+		createStmts = append(createStmts, TableStructure{
+			DDL:    strings.TrimSpace(strings.Join(append([]string{stmt}, relation.comments...), "\n")),
+			Name:   relation.name,
+			Schema: relation.schema,
+			Type:   relationType,
+		})
+	}
+
+	return createStmts
+}
+
+// getPostgresEnumDDL returns a CREATE TYPE statement for each enum type of `enumLabels`
+func getPostgresEnumDDL(enumLabels []postgresEnumLabel) []TableStructure {
+	var typeNames []string
+	allTypes := make(map[string]TableStructure)
+	allTypesAndLabels := make(map[string][]string)
+
+	for _, enumLabel := range enumLabels {
+		fullTypeName := toPostgresName(enumLabel.Schema, enumLabel.Type)
+
+		labels, ok := allTypesAndLabels[fullTypeName]
+		if !ok {
+			allTypes[fullTypeName] = TableStructure{
+				Name:   enumLabel.Type,
+				Schema: enumLabel.Schema,
+				Type:   "type",
+			}
+			typeNames = append(typeNames, fullTypeName)
+		}
+
+		allTypesAndLabels[fullTypeName] = append(labels, toPostgresString(enumLabel.Label))
+	}
+
+	var createStmts []TableStructure
+	for _, typeName := range typeNames {
+		enum := allTypes[typeName]
+		enum.DDL = fmt.Sprintf(
+			"CREATE TYPE %v AS ENUM (%v);",
+			typeName,
+			strings.Join(allTypesAndLabels[typeName], ","),
+		)
+
+		createStmts = append(createStmts, enum)
+	}
+
+	return createStmts
+}
+
+// getPostgresEnums returns the labels of all enum types in `schemas`
+func getPostgresEnums(db *sql.DB, schemas []string) ([]postgresEnumLabel, error) {
+	query := `SELECT n.nspname, t.typname, e.enumlabel
+FROM pg_catalog.pg_type t
+INNER JOIN pg_catalog.pg_enum e ON e.enumtypid = t.oid
+INNER JOIN pg_catalog.pg_namespace n ON n.oid = t.typnamespace
+WHERE n.nspname = ANY($1)
+ORDER BY n.nspname, t.typname, e.enumsortorder;`
+
+	rows, err := db.Query(query, pq.Array(schemas))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var enumLabels []postgresEnumLabel
+	for rows.Next() {
+		var enumLabel postgresEnumLabel
+
+		err := rows.Scan(&enumLabel.Schema, &enumLabel.Type, &enumLabel.Label)
+		if err != nil {
+			return nil, err
+		}
+
+		enumLabels = append(enumLabels, enumLabel)
+	}
+
+	return enumLabels, rows.Err()
+}
+
+// getPostgresTables returns the DDL of all enums, tables and views in
+// `schemas`, including constraints and comments
+func getPostgresTables(db *sql.DB, schemas []string) ([]TableStructure, error) {
+	if len(schemas) == 0 {
+		schemas = []string{"public"}
+	}
+
+	columns, err := getPostgresColumns(db, schemas)
+	if err != nil {
+		return []TableStructure{}, err
+	}
+
+	constraints, err := getPostgresConstraints(db, schemas)
+	if err != nil {
+		return []TableStructure{}, err
+	}
+
+	enumLabels, err := getPostgresEnums(db, schemas)
+	if err != nil {
+		return []TableStructure{}, err
+	}
+
+	return getPostgresDDL(columns, constraints, enumLabels), nil
+}
+
+// getRowLimitInstruction returns the instruction for the system prompt,
+// which limits the number of rows in the SQL dialect of `dbName`
+func getRowLimitInstruction(dbName string) string {
+	if dbName == "Microsoft SQL Server" {
+		return "Make sure that a user gets back a maximum of 1000 rows per SQL query by using TOP 1000 in T-SQL. Never use LIMIT, because it is not supported."
+	}
+
+	return "Make sure that a user gets back a maximum of 1000 rows per SQL query."
+}
+
+// getSQLServerForeignKeys returns the FOREIGN KEY constraints of all
+// tables, grouped by the full name of the table
+func getSQLServerForeignKeys(db *sql.DB) (map[string][]string, error) {
+	query := `SELECT fk.name,
+OBJECT_SCHEMA_NAME(fk.parent_object_id), OBJECT_NAME(fk.parent_object_id), COL_NAME(fkc.parent_object_id, fkc.parent_column_id),
+OBJECT_SCHEMA_NAME(fk.referenced_object_id), OBJECT_NAME(fk.referenced_object_id), COL_NAME(fkc.referenced_object_id, fkc.referenced_column_id)
+FROM sys.foreign_keys fk
+INNER JOIN sys.foreign_key_columns fkc ON fkc.constraint_object_id = fk.object_id
+ORDER BY fk.name, fkc.constraint_column_id;`
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	type foreignKey struct {
+		columns           []string
+		referencedColumns []string
+		referencedTable   string
+		table             string
+	}
+
+	var foreignKeyNames []string
+	allForeignKeys := make(map[string]*foreignKey)
+
+	for rows.Next() {
+		var name string
+		var tableSchema string
+		var tableName string
+		var columnName string
+		var referencedTableSchema string
+		var referencedTableName string
+		var referencedColumnName string
+
+		err := rows.Scan(
+			&name,
+			&tableSchema, &tableName, &columnName,
+			&referencedTableSchema, &referencedTableName, &referencedColumnName,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		key := toSQLServerName(tableSchema, name)
+
+		fk, ok := allForeignKeys[key]
+		if !ok {
+			fk = &foreignKey{
+				referencedTable: toSQLServerName(referencedTableSchema, referencedTableName),
+				table:           toSQLServerName(tableSchema, tableName),
+			}
+
+			allForeignKeys[key] = fk
+			foreignKeyNames = append(foreignKeyNames, key)
+		}
+
+		fk.columns = append(fk.columns, toSQLServerName(columnName))
+		fk.referencedColumns = append(fk.referencedColumns, toSQLServerName(referencedColumnName))
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	foreignKeys := make(map[string][]string)
+	for _, key := range foreignKeyNames {
+		fk := allForeignKeys[key]
+
+		foreignKeys[fk.table] = append(
+			foreignKeys[fk.table],
+			fmt.Sprintf(
+				"FOREIGN KEY (%v) REFERENCES %v (%v)",
+				strings.Join(fk.columns, ","),
+				fk.referencedTable,
+				strings.Join(fk.referencedColumns, ","),
+			),
+		)
+	}
+
+	return foreignKeys, nil
+}
+
+// getSQLServerPrimaryKeys returns the columns of the PRIMARY KEY constraints
+// of all tables, grouped by the full name of the table
+func getSQLServerPrimaryKeys(db *sql.DB) (map[string][]string, error) {
+	query := `SELECT kcu.TABLE_SCHEMA, kcu.TABLE_NAME, kcu.COLUMN_NAME
+FROM INFORMATION_SCHEMA.TABLE_CONSTRAINTS tc
+INNER JOIN INFORMATION_SCHEMA.KEY_COLUMN_USAGE kcu ON kcu.CONSTRAINT_SCHEMA = tc.CONSTRAINT_SCHEMA AND kcu.CONSTRAINT_NAME = tc.CONSTRAINT_NAME
+WHERE tc.CONSTRAINT_TYPE = 'PRIMARY KEY'
+ORDER BY kcu.TABLE_SCHEMA, kcu.TABLE_NAME, kcu.ORDINAL_POSITION;`
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	primaryKeys := make(map[string][]string)
+	for rows.Next() {
+		var tableSchema string
+		var tableName string
+		var columnName string
+
+		err := rows.Scan(&tableSchema, &tableName, &columnName)
+		if err != nil {
+			return nil, err
+		}
+
+		fullTableName := toSQLServerName(tableSchema, tableName)
+
+		primaryKeys[fullTableName] = append(primaryKeys[fullTableName], toSQLServerName(columnName))
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return primaryKeys, nil
+}
+
+func getSQLServerTables(db *sql.DB) ([]TableStructure, error) {
+	query := `SELECT c.TABLE_SCHEMA, c.TABLE_NAME, c.COLUMN_NAME, c.DATA_TYPE, c.CHARACTER_MAXIMUM_LENGTH, c.IS_NULLABLE
+FROM INFORMATION_SCHEMA.COLUMNS c
+INNER JOIN INFORMATION_SCHEMA.TABLES t ON t.TABLE_SCHEMA = c.TABLE_SCHEMA AND t.TABLE_NAME = c.TABLE_NAME
+WHERE t.TABLE_TYPE = 'BASE TABLE'
+ORDER BY c.TABLE_SCHEMA, c.TABLE_NAME, c.ORDINAL_POSITION;`
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return []TableStructure{}, err
+	}
+
+	defer rows.Close()
+
+	var tableNames []string
+	allTables := make(map[string]TableStructure)
+	allTablesAndColumns := make(map[string][]string)
+
+	for rows.Next() {
+		var tableSchema string
+		var tableName string
+		var columnName string
+		var dataType string
+		var maxLength sql.NullInt64
+		var isNullable string
+
+		err := rows.Scan(&tableSchema, &tableName, &columnName, &dataType, &maxLength, &isNullable)
+		if err != nil {
+			return []TableStructure{}, err
+		}
+
+		fullTableName := toSQLServerName(tableSchema, tableName)
+
+		tableCols, ok := allTablesAndColumns[fullTableName]
+		if !ok {
+			allTables[fullTableName] = TableStructure{
+				Name:   tableName,
+				Schema: tableSchema,
+				Type:   "table",
+			}
+			tableNames = append(tableNames, fullTableName)
+		}
+
+		if maxLength.Valid {
+			if maxLength.Int64 < 0 {
+				dataType += "(max)"
+			} else {
+				dataType += fmt.Sprintf("(%v)", maxLength.Int64)
+			}
+		}
+
+		notNull := ""
+		if isNullable == "NO" {
+			notNull = "NOT NULL"
+		}
+
+		stmt := fmt.Sprintf(
+			`%v %v %v`,
+			toSQLServerName(columnName), dataType, notNull,
+		)
+
+		allTablesAndColumns[fullTableName] = append(
+			tableCols,
 			strings.TrimSpace(stmt),
 		)
 	}
 
+	err = rows.Err()
+	if err != nil {
+		return []TableStructure{}, err
+	}
+
+	primaryKeys, err := getSQLServerPrimaryKeys(db)
+	if err != nil {
+		return []TableStructure{}, err
+	}
+
+	foreignKeys, err := getSQLServerForeignKeys(db)
+	if err != nil {
+		return []TableStructure{}, err
+	}
+
+	var createStmts []TableStructure
+
+	for _, tableName := range tableNames {
+		tableCols := allTablesAndColumns[tableName]
+		if len(primaryKeys[tableName]) > 0 {
+			tableCols = append(tableCols, fmt.Sprintf("PRIMARY KEY (%v)", strings.Join(primaryKeys[tableName], ",")))
+		}
+		tableCols = append(tableCols, foreignKeys[tableName]...)
+
+		stmt := fmt.Sprintf(
+			`CREATE TABLE %v (%v);`,
+			tableName,
+			strings.Join(tableCols, ","),
+		)
+
+		table := allTables[tableName]
+		table.DDL = strings.TrimSpace(stmt)
+
+		createStmts = append(createStmts, table)
+	}
+
 	return createStmts, nil
 }
 
+func getSQLiteTables(db *sql.DB) ([]TableStructure, error) {
+	rows, err := db.Query(`SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name;`)
+	if err != nil {
+		return []TableStructure{}, err
+	}
+
+	var tableNames []string
+	for rows.Next() {
+		var tableName string
+
+		err := rows.Scan(&tableName)
+		if err != nil {
+			rows.Close()
+			return []TableStructure{}, err
+		}
+
+		tableNames = append(tableNames, tableName)
+	}
+	rows.Close()
+
+	var createStmts []TableStructure
+
+	for _, tableName := range tableNames {
+		var tableCols []string
+		var primaryKeys []string
+
+		err := func() error {
+			rows, err := db.Query(fmt.Sprintf(`PRAGMA table_info("%v");`, strings.ReplaceAll(tableName, `"`, `""`)))
+			if err != nil {
+				return err
+			}
+
+			defer rows.Close()
+
+			for rows.Next() {
+				var cid int
+				var columnName string
+				var dataType string
+				var notNull int
+				var defaultValue sql.NullString
+				var primaryKey int
+
+				err := rows.Scan(&cid, &columnName, &dataType, &notNull, &defaultValue, &primaryKey)
+				if err != nil {
+					return err
+				}
+
+				notNullStr := ""
+				if notNull != 0 {
+					notNullStr = "NOT NULL"
+				}
+
+				if primaryKey > 0 {
+					primaryKeys = append(primaryKeys, fmt.Sprintf(`"%v"`, columnName))
+				}
+
+				stmt := fmt.Sprintf(
+					`"%v" %v %v`,
+					columnName, dataType, notNullStr,
+				)
+
+				tableCols = append(
+					tableCols,
+					strings.TrimSpace(stmt),
+				)
+			}
+
+			return rows.Err()
+		}()
+		if err != nil {
+			return []TableStructure{}, err
+		}
+
+		if len(primaryKeys) > 0 {
+			tableCols = append(tableCols, fmt.Sprintf("PRIMARY KEY (%v)", strings.Join(primaryKeys, ",")))
+		}
+
+		stmt := fmt.Sprintf(
+			`CREATE TABLE "%v" (%v);`,
+			tableName,
+			strings.Join(tableCols, ","),
+		)
+
+		createStmts = append(createStmts, TableStructure{
+			DDL:  strings.TrimSpace(stmt),
+			Name: tableName,
+			Type: "table",
+		})
+	}
+
+	return createStmts, nil
+}
+
+// toPostgresName quotes and joins the identifiers of `names` with dots
+func toPostgresName(names ...string) string {
+	quotedNames := make([]string, 0, len(names))
+	for _, name := range names {
+		quotedNames = append(quotedNames, `"`+strings.ReplaceAll(name, `"`, `""`)+`"`)
+	}
+
+	return strings.Join(quotedNames, ".")
+}
+
+// toPostgresString quotes `str` as string literal
+func toPostgresString(str string) string {
+	return "'" + strings.ReplaceAll(str, "'", "''") + "'"
+}
+
+// toSQLServerName quotes and joins the identifiers of `names` with dots
+func toSQLServerName(names ...string) string {
+	quotedNames := make([]string, 0, len(names))
+	for _, name := range names {
+		quotedNames = append(quotedNames, "["+strings.ReplaceAll(name, "]", "]]")+"]")
+	}
+
+	return strings.Join(quotedNames, ".")
+}
+
 func Init_sql_Command(rootCmd *cobra.Command) {
 	var asCSV bool
+	var chatOpts chatOptions
 	var connectionStr string
+	var excludeTablePatterns []string
 	var openEditor bool
+	var schemas []string
+	var tablePatterns []string
 	var temperature float64
 
 	sqlCmd := &cobra.Command{
@@ -108,13 +805,26 @@ func Init_sql_Command(rootCmd *cobra.Command) {
 
 			defer db.Close()
 
-			var getTables GetTablesFunc = func() ([]string, error) {
-				return []string{}, errors.New("not implemented yet")
+			var getTables GetTablesFunc = func() ([]TableStructure, error) {
+				return []TableStructure{}, errors.New("not implemented yet")
 			}
 
-			if dbName == "PostgreSQL" {
-				getTables = func() ([]string, error) {
-					return getPostgresTables(db)
+			switch dbName {
+			case "MariaDB", "MySQL":
+				getTables = func() ([]TableStructure, error) {
+					return getMySQLTables(db)
+				}
+			case "Microsoft SQL Server":
+				getTables = func() ([]TableStructure, error) {
+					return getSQLServerTables(db)
+				}
+			case "PostgreSQL":
+				getTables = func() ([]TableStructure, error) {
+					return getPostgresTables(db, schemas)
+				}
+			case "SQLite":
+				getTables = func() ([]TableStructure, error) {
+					return getSQLiteTables(db)
 				}
 			}
 
@@ -123,10 +833,23 @@ func Init_sql_Command(rootCmd *cobra.Command) {
 				panic(err)
 			}
 
+			tableStructures = filterTableStructures(tableStructures, tablePatterns, excludeTablePatterns)
 			if len(tableStructures) < 1 {
 				panic(fmt.Errorf("no tables found in %v database", dbName))
 			}
 
+			// large schemas do not fit into the system prompt
+			tableStructures, err = useRelevantTables(cmd.Context(), chatOpts, dbName, question, tableStructures)
+			if err != nil {
+				panic(err)
+			}
+
+			if len(tableStructures) < 1 {
+				fmt.Println("no relevant tables found")
+
+				os.Exit(2)
+			}
+
 			now := time.Now()
 			zoneName, zoneOffset := now.Zone()
 
@@ -143,9 +866,9 @@ Users have access to one of your database with the following structure:
 %v
 
 Users are only able to submit queries as natural human language. Users are also able to send a list of natural human language queries.
-You will only return valid SQL queries based on the given database schema without any explanation. Only SELECT statements. Nothing else.
-A query from a user can also result in multiple SQL queries. So wrap each SQL string into a single and valid JSON array without anything else.
-Make sure that a user gets back a maximum of 1000 rows per SQL query.
+You will only return valid SQL queries in the SQL dialect of %v based on the given database schema without any explanation. Only SELECT statements. Nothing else.
+A query from a user can also result in multiple SQL queries. So return a single and valid JSON object with a property "statements", which is an array of all SQL strings, without anything else.
+%v
 You will never change the case of the entries and you will never work with entities which are not in the given list.
 Keep sure that you search strings case insensitive and ignore leading and ending whitespace values.
 You are not allowed to ask for more details.
@@ -153,7 +876,9 @@ You are not allowed to explain anything.
 You are not allowed to give your opinion.
 You are not allowed to tell about any kind of limitations.`,
 					dbName,
-					strings.Join(tableStructures, "\n"),
+					getTableDDL(tableStructures),
+					dbName,
+					getRowLimitInstruction(dbName),
 				),
 			)
 
@@ -186,20 +911,19 @@ You are not allowed to tell about any kind of limitations.`,
 				systemPrompt.WriteString(fmt.Sprintln())
 			}
 
-			answer, err := egoOpenAI.AskChatGPT(
-				strings.TrimSpace(systemPrompt.String()),
-				temperature,
-				question,
+			var answer struct {
+				Statements []string `json:"statements"`
+			}
+			_, err = completeJSON(
+				cmd.Context(),
+				chatOpts.newRequest(strings.TrimSpace(systemPrompt.String()), temperature, question),
+				&answer,
 			)
 			if err != nil {
 				panic(err)
 			}
 
-			var sqlStmts []string
-			err = json.Unmarshal([]byte(answer), &sqlStmts)
-			if err != nil {
-				panic(err)
-			}
+			sqlStmts := answer.Statements
 
 			if len(sqlStmts) < 1 {
 				fmt.Println("no SQL statements returned")
@@ -288,7 +1012,11 @@ You are not allowed to tell about any kind of limitations.`,
 	sqlCmd.Flags().BoolVarP(&asCSV, "csv", "", false, "Output as CSV")
 	sqlCmd.Flags().BoolVarP(&openEditor, "editor", "e", false, "Open editor for input")
 	sqlCmd.Flags().StringVarP(&connectionStr, "connection", "c", "", "Open editor for input")
+	sqlCmd.Flags().StringArrayVarP(&excludeTablePatterns, "exclude-tables", "", []string{}, "Glob patterns of tables to ignore, like audit_*")
+	sqlCmd.Flags().StringArrayVarP(&tablePatterns, "tables", "", []string{}, "Glob patterns of the only tables to use, like sales.*")
+	sqlCmd.Flags().StringArrayVarP(&schemas, "schema", "", []string{}, "PostgreSQL schema to use, default is public")
 	sqlCmd.Flags().Float64VarP(&temperature, "temperature", "t", getDefaultTemperature(), "Custom temperature between 0 and 2")
+	addChatOptionFlags(sqlCmd, &chatOpts)
 
 	rootCmd.AddCommand(sqlCmd)
 }
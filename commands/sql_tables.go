@@ -0,0 +1,247 @@
+// This file is part of the e.GPT distribution.
+// Copyright (c) Next.e.GO Mobile SE, Aachen, Germany (https://e-go-mobile.com/)
+//
+// e-gpt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, version 3.
+//
+// e-gpt is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	egoOpenAI "github.com/egomobile/e-gpt/openai"
+	egoUtils "github.com/egomobile/e-gpt/utils"
+)
+
+// maximum number of schema tokens, if the context limit of the model is unknown
+const defaultSQLSchemaMaxTokens = 8192
+
+// TableStructure describes a table, view or type of a database.
+type TableStructure struct {
+	DDL    string // The CREATE statement and optional comments
+	Name   string // The name, without schema
+	Schema string // The schema, if supported by the database
+	Type   string // `table`, `view` or `type`
+}
+
+// FullName returns the name of the table, including the schema, if defined.
+func (t TableStructure) FullName() string {
+	if t.Schema == "" {
+		return t.Name
+	}
+
+	return t.Schema + "." + t.Name
+}
+
+// filterTableStructures returns the tables and views of `tables`, which match
+// one of `includePatterns`, if defined, and none of `excludePatterns`, with all
+// types, they reference
+func filterTableStructures(tables []TableStructure, includePatterns []string, excludePatterns []string) []TableStructure {
+	includePatterns = splitTablePatterns(includePatterns)
+	excludePatterns = splitTablePatterns(excludePatterns)
+
+	var filteredTables []TableStructure
+	for _, table := range tables {
+		if table.Type != "type" {
+			if len(includePatterns) > 0 && !matchesTablePatterns(table, includePatterns) {
+				continue
+			}
+			if matchesTablePatterns(table, excludePatterns) {
+				continue
+			}
+		}
+
+		filteredTables = append(filteredTables, table)
+	}
+
+	return withReferencedTypes(filteredTables)
+}
+
+// getSQLSchemaMaxTokens returns the maximum number of tokens of the table
+// structures for the system prompt, before relevant tables are selected first
+func getSQLSchemaMaxTokens(request egoOpenAI.CompletionRequest) int {
+	str := strings.TrimSpace(os.Getenv("CHAT_SQL_MAX_SCHEMA_TOKENS"))
+	if str != "" {
+		val, err := strconv.Atoi(str)
+		if err == nil && val > 0 {
+			return val
+		}
+	}
+
+	model, err := egoOpenAI.GetModel(request)
+	if err != nil {
+		return defaultSQLSchemaMaxTokens
+	}
+
+	contextLimit, ok := egoOpenAI.LookupContextLimit(model)
+	if !ok {
+		return defaultSQLSchemaMaxTokens
+	}
+
+	// keep the other half for the instructions, question and answer
+	return contextLimit / 2
+}
+
+// getTableDDL returns the DDL of all items of `tables`
+func getTableDDL(tables []TableStructure) string {
+	ddl := make([]string, 0, len(tables))
+	for _, table := range tables {
+		ddl = append(ddl, table.DDL)
+	}
+
+	return strings.Join(ddl, "\n")
+}
+
+// matchesTablePatterns checks if the name of `table`, with or without schema,
+// matches one of the glob `patterns`
+func matchesTablePatterns(table TableStructure, patterns []string) bool {
+	names := []string{
+		strings.ToLower(table.Name),
+		strings.ToLower(table.FullName()),
+	}
+
+	for _, pattern := range patterns {
+		for _, name := range names {
+			isMatch, err := path.Match(strings.ToLower(pattern), name)
+			if err == nil && isMatch {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// selectRelevantTables asks the chat API, which of `tables` are required
+// to answer `question` and returns them
+func selectRelevantTables(ctx context.Context, chatOpts chatOptions, dbName string, question string, tables []TableStructure) ([]TableStructure, error) {
+	var tableList strings.Builder
+	for _, table := range tables {
+		if table.Type != "type" {
+			tableList.WriteString(fmt.Sprintf("- %v (%v)\n", table.FullName(), table.Type))
+		}
+	}
+
+	systemPrompt := fmt.Sprintf(`You are a developer of a %v database, which contains the following tables and views:
+%v
+Users are only able to submit queries as natural human language.
+You will only return a JSON object with a property "tables", which is an array of the names of all tables and views, which are required to answer the query of the user with SQL, including the ones required for joins.
+Write the names exactly as in the list above.
+You are not allowed to explain anything.`,
+		dbName,
+		tableList.String(),
+	)
+
+	var answer struct {
+		Tables []string `json:"tables"`
+	}
+	_, err := completeJSON(
+		ctx,
+		chatOpts.newRequest(systemPrompt, 0, question),
+		&answer,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	selectedNames := make(map[string]bool)
+	for _, name := range answer.Tables {
+		selectedNames[strings.ToLower(strings.TrimSpace(name))] = true
+	}
+
+	var selectedTables []TableStructure
+	for _, table := range tables {
+		if table.Type == "type" {
+			continue
+		}
+
+		if selectedNames[strings.ToLower(table.FullName())] || selectedNames[strings.ToLower(table.Name)] {
+			selectedTables = append(selectedTables, table)
+		}
+	}
+
+	return withReferencedTypes(selectedTables), nil
+}
+
+// splitTablePatterns splits comma separated items of `patterns`
+func splitTablePatterns(patterns []string) []string {
+	var finalPatterns []string
+	for _, pattern := range patterns {
+		for _, item := range strings.Split(pattern, ",") {
+			item = strings.TrimSpace(item)
+			if item != "" {
+				finalPatterns = append(finalPatterns, item)
+			}
+		}
+	}
+
+	return finalPatterns
+}
+
+// withReferencedTypes returns the tables and views of `tables` and only the
+// types, which are referenced by them
+func withReferencedTypes(tables []TableStructure) []TableStructure {
+	var finalTables []TableStructure
+	for _, table := range tables {
+		if table.Type == "type" {
+			continue
+		}
+
+		finalTables = append(finalTables, table)
+	}
+
+	ddl := getTableDDL(finalTables)
+
+	var types []TableStructure
+	for _, table := range tables {
+		if table.Type == "type" && strings.Contains(ddl, table.Name) {
+			types = append(types, table)
+		}
+	}
+
+	// types have to be defined first
+	return append(types, finalTables...)
+}
+
+// useRelevantTables returns `tables`, if their DDL fits into the system
+// prompt, otherwise only the ones, which are relevant for `question`
+func useRelevantTables(ctx context.Context, chatOpts chatOptions, dbName string, question string, tables []TableStructure) ([]TableStructure, error) {
+	tokenCount, err := egoOpenAI.CountTokens(getTableDDL(tables))
+	if err != nil {
+		return nil, err
+	}
+
+	maxTokens := getSQLSchemaMaxTokens(chatOpts.newRequest("", 0, question))
+	if tokenCount <= maxTokens {
+		return tables, nil
+	}
+
+	egoUtils.LogVerbose("schema has %v tokens, which is more than %v: selecting relevant tables", tokenCount, maxTokens)
+
+	relevantTables, err := selectRelevantTables(ctx, chatOpts, dbName, question, tables)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, table := range relevantTables {
+		names = append(names, table.FullName())
+	}
+	egoUtils.LogVerbose("relevant tables: %v", strings.Join(names, ", "))
+
+	return relevantTables, nil
+}
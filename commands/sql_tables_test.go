@@ -0,0 +1,125 @@
+// This file is part of the e.GPT distribution.
+// Copyright (c) Next.e.GO Mobile SE, Aachen, Germany (https://e-go-mobile.com/)
+//
+// e-gpt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, version 3.
+//
+// e-gpt is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package commands
+
+import (
+	"reflect"
+	"testing"
+
+	egoOpenAI "github.com/egomobile/e-gpt/openai"
+)
+
+func getTableNames(tables []TableStructure) []string {
+	var names []string
+	for _, table := range tables {
+		names = append(names, table.FullName())
+	}
+
+	return names
+}
+
+func TestFilterTableStructures(t *testing.T) {
+	tables := []TableStructure{
+		{DDL: `CREATE TYPE "public"."mood" AS ENUM ('happy');`, Name: "mood", Schema: "public", Type: "type"},
+		{DDL: `CREATE TYPE "public"."unused" AS ENUM ('a');`, Name: "unused", Schema: "public", Type: "type"},
+		{DDL: `CREATE TABLE "public"."users" ("mood" "public"."mood");`, Name: "users", Schema: "public", Type: "table"},
+		{DDL: `CREATE TABLE "public"."orders" ("id" integer);`, Name: "orders", Schema: "public", Type: "table"},
+		{DDL: `CREATE TABLE "audit"."user_logs" ("id" integer);`, Name: "user_logs", Schema: "audit", Type: "table"},
+		{DDL: `CREATE VIEW "public"."active_users" AS SELECT 1;`, Name: "active_users", Schema: "public", Type: "view"},
+	}
+
+	tests := []struct {
+		include  []string
+		exclude  []string
+		expected []string
+	}{
+		{nil, nil, []string{"public.mood", "public.users", "public.orders", "audit.user_logs", "public.active_users"}},
+		{[]string{"users"}, nil, []string{"public.mood", "public.users"}},
+		{[]string{"USER*"}, nil, []string{"public.mood", "public.users", "audit.user_logs"}},
+		{[]string{"audit.*, orders"}, nil, []string{"public.orders", "audit.user_logs"}},
+		{nil, []string{"audit.*", "*_users"}, []string{"public.mood", "public.users", "public.orders"}},
+		{[]string{"*user*"}, []string{"users"}, []string{"audit.user_logs", "public.active_users"}},
+	}
+
+	for _, test := range tests {
+		names := getTableNames(filterTableStructures(tables, test.include, test.exclude))
+		if !reflect.DeepEqual(names, test.expected) {
+			t.Errorf("filterTableStructures(%q, %q) = %q; expected %q", test.include, test.exclude, names, test.expected)
+		}
+	}
+}
+
+func TestMatchesTablePatterns(t *testing.T) {
+	table := TableStructure{Name: "Users", Schema: "dbo", Type: "table"}
+
+	tests := []struct {
+		patterns []string
+		isMatch  bool
+	}{
+		{nil, false},
+		{[]string{"users"}, true},
+		{[]string{"dbo.users"}, true},
+		{[]string{"DBO.U*"}, true},
+		{[]string{"sales.users"}, false},
+		{[]string{"orders", "user?"}, true},
+		{[]string{"[invalid"}, false},
+	}
+
+	for _, test := range tests {
+		isMatch := matchesTablePatterns(table, test.patterns)
+		if isMatch != test.isMatch {
+			t.Errorf("matchesTablePatterns(%q) = %v; expected %v", test.patterns, isMatch, test.isMatch)
+		}
+	}
+}
+
+func TestSplitTablePatterns(t *testing.T) {
+	patterns := splitTablePatterns([]string{"users, orders", " ", "audit.*,,"})
+
+	expected := []string{"users", "orders", "audit.*"}
+	if !reflect.DeepEqual(patterns, expected) {
+		t.Fatalf("unexpected patterns %q", patterns)
+	}
+}
+
+func TestGetSQLSchemaMaxTokens(t *testing.T) {
+	t.Setenv("CHAT_API_CONTEXT_LIMIT", "")
+	t.Setenv("CHAT_API_MODEL", "")
+	t.Setenv("CHAT_API_PROVIDER", "openai")
+	t.Setenv("OPENAI_API_KEY", "test")
+	t.Setenv("CHAT_SQL_MAX_SCHEMA_TOKENS", "")
+
+	tests := map[string]int{
+		"":              16385 / 2, // default model of OpenAI
+		"gpt-4":         4096,
+		"gpt-4.1":       1047576 / 2,
+		"gpt-4o":        64000,
+		"unknown-model": defaultSQLSchemaMaxTokens,
+	}
+
+	for model, expected := range tests {
+		maxTokens := getSQLSchemaMaxTokens(egoOpenAI.CompletionRequest{Model: model})
+		if maxTokens != expected {
+			t.Errorf("getSQLSchemaMaxTokens() of %v = %v; expected %v", model, maxTokens, expected)
+		}
+	}
+
+	t.Setenv("CHAT_SQL_MAX_SCHEMA_TOKENS", "1234")
+	maxTokens := getSQLSchemaMaxTokens(egoOpenAI.CompletionRequest{Model: "gpt-4"})
+	if maxTokens != 1234 {
+		t.Errorf("expected value of CHAT_SQL_MAX_SCHEMA_TOKENS, got %v", maxTokens)
+	}
+}
@@ -0,0 +1,133 @@
+// This file is part of the e.GPT distribution.
+// Copyright (c) Next.e.GO Mobile SE, Aachen, Germany (https://e-go-mobile.com/)
+//
+// e-gpt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, version 3.
+//
+// e-gpt is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package commands
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+)
+
+func TestToPostgresName(t *testing.T) {
+	tests := map[string][]string{
+		`"users"`:              {"users"},
+		`"public"."users"`:     {"public", "users"},
+		`"my ""quoted"" name"`: {`my "quoted" name`},
+	}
+
+	for expected, names := range tests {
+		name := toPostgresName(names...)
+		if name != expected {
+			t.Errorf("toPostgresName(%q) = %v; expected %v", names, name, expected)
+		}
+	}
+}
+
+func TestToSQLServerName(t *testing.T) {
+	tests := map[string][]string{
+		`[users]`:            {"users"},
+		`[dbo].[users]`:      {"dbo", "users"},
+		`[dbo].[a]]b]`:       {"dbo", "a]b"},
+		`[[bracketed]]]`:     {"[bracketed]"},
+		`[with space].[x y]`: {"with space", "x y"},
+	}
+
+	for expected, names := range tests {
+		name := toSQLServerName(names...)
+		if name != expected {
+			t.Errorf("toSQLServerName(%q) = %v; expected %v", names, name, expected)
+		}
+	}
+}
+
+func TestGetPostgresDDL(t *testing.T) {
+	comment := func(str string) sql.NullString {
+		return sql.NullString{String: str, Valid: true}
+	}
+
+	columns := []postgresColumn{
+		{Schema: "public", Relation: "orders", RelationKind: "r", Name: "id", DataType: "integer", IsNotNull: true},
+		{Schema: "public", Relation: "orders", RelationKind: "r", Name: "user_id", DataType: "integer", IsNotNull: true},
+		{Schema: "public", Relation: "orders", RelationKind: "r", Name: "note", DataType: "text"},
+		{Schema: "public", Relation: "user's", RelationKind: "p", RelationComment: comment("all users"), Name: "id", DataType: "integer", IsNotNull: true, Comment: comment("it's the ID")},
+		{Schema: "public", Relation: "user's", RelationKind: "p", RelationComment: comment("all users"), Name: "mood", DataType: "mood"},
+		{Schema: "public", Relation: "big_orders", RelationKind: "v", RelationComment: comment("orders with a note"), ViewQuery: comment(" SELECT id\n   FROM orders\n  WHERE note IS NOT NULL;"), Name: "id", DataType: "integer"},
+		{Schema: "audit", Relation: "stats", RelationKind: "m", ViewQuery: comment("SELECT count(*) AS count FROM orders"), Name: "count", DataType: "bigint"},
+	}
+
+	constraints := []postgresConstraint{
+		{Schema: "public", Table: "orders", Name: "orders_pkey", Definition: "PRIMARY KEY (id)"},
+		{Schema: "public", Table: "orders", Name: "orders_user_id_fkey", Definition: `FOREIGN KEY (user_id) REFERENCES "user's"(id)`},
+		{Schema: "public", Table: "user's", Name: "users_pkey", Definition: "PRIMARY KEY (id)"},
+	}
+
+	enumLabels := []postgresEnumLabel{
+		{Schema: "public", Type: "mood", Label: "happy"},
+		{Schema: "public", Type: "mood", Label: "it's ok"},
+	}
+
+	expected := []TableStructure{
+		{
+			DDL:    `CREATE TYPE "public"."mood" AS ENUM ('happy','it''s ok');`,
+			Name:   "mood",
+			Schema: "public",
+			Type:   "type",
+		},
+		{
+			DDL:    `CREATE TABLE "public"."orders" ("id" integer NOT NULL,"user_id" integer NOT NULL,"note" text,CONSTRAINT "orders_pkey" PRIMARY KEY (id),CONSTRAINT "orders_user_id_fkey" FOREIGN KEY (user_id) REFERENCES "user's"(id));`,
+			Name:   "orders",
+			Schema: "public",
+			Type:   "table",
+		},
+		{
+			DDL: `CREATE TABLE "public"."user's" ("id" integer NOT NULL,"mood" mood,CONSTRAINT "users_pkey" PRIMARY KEY (id));
+COMMENT ON TABLE "public"."user's" IS 'all users';
+COMMENT ON COLUMN "public"."user's"."id" IS 'it''s the ID';`,
+			Name:   "user's",
+			Schema: "public",
+			Type:   "table",
+		},
+		{
+			DDL: `CREATE VIEW "public"."big_orders" ("id") AS SELECT id
+   FROM orders
+  WHERE note IS NOT NULL;
+COMMENT ON VIEW "public"."big_orders" IS 'orders with a note';`,
+			Name:   "big_orders",
+			Schema: "public",
+			Type:   "view",
+		},
+		{
+			DDL:    `CREATE MATERIALIZED VIEW "audit"."stats" ("count") AS SELECT count(*) AS count FROM orders;`,
+			Name:   "stats",
+			Schema: "audit",
+			Type:   "view",
+		},
+	}
+
+	tables := getPostgresDDL(columns, constraints, enumLabels)
+	if len(tables) != len(expected) {
+		t.Fatalf("expected %v structures, got %+v", len(expected), tables)
+	}
+	for i, table := range tables {
+		if !reflect.DeepEqual(table, expected[i]) {
+			t.Errorf("structure %v is\n%v\nexpected\n%v", i, table.DDL, expected[i].DDL)
+		}
+	}
+
+	if len(getPostgresDDL(nil, nil, nil)) != 0 {
+		t.Error("expected no structures without columns and enums")
+	}
+}
@@ -0,0 +1,180 @@
+// This file is part of the e.GPT distribution.
+// Copyright (c) Next.e.GO Mobile SE, Aachen, Germany (https://e-go-mobile.com/)
+//
+// e-gpt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, version 3.
+//
+// e-gpt is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package commands
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/alecthomas/chroma/quick"
+
+	egoOpenAI "github.com/egomobile/e-gpt/openai"
+	egoUtils "github.com/egomobile/e-gpt/utils"
+)
+
+// outputAnswerOptions contains options for outputAnswer()
+type outputAnswerOptions struct {
+	Lexer     string // the lexer to use for highlighting, empty for Markdown
+	NoNewLine bool   // do not add new line at the end
+	NoStream  bool   // wait for the complete answer, before writing to STDOUT
+	PlainText bool   // do not highlight the answer
+}
+
+// completeAnswer sends `request` to the chat API and returns the complete
+// answer. Pressing Ctrl+C aborts the request and exits the process.
+func completeAnswer(ctx context.Context, request egoOpenAI.CompletionRequest) (egoOpenAI.CompletionResponse, error) {
+	interruptCtx, stop := withInterrupt(ctx)
+	defer stop()
+
+	response, err := egoOpenAI.Complete(interruptCtx, request)
+	exitIfInterrupted(ctx, interruptCtx)
+
+	return response, err
+}
+
+// completeJSON sends `request` to the chat API with egoOpenAI.AskJSON() and
+// parses the answer into `result`. Pressing Ctrl+C aborts the request and
+// exits the process.
+func completeJSON(ctx context.Context, request egoOpenAI.CompletionRequest, result interface{}) (egoOpenAI.CompletionResponse, error) {
+	interruptCtx, stop := withInterrupt(ctx)
+	defer stop()
+
+	response, err := egoOpenAI.AskJSON(interruptCtx, request, result)
+	exitIfInterrupted(ctx, interruptCtx)
+
+	return response, err
+}
+
+// exitIfInterrupted exits the process with code 130, if `interruptCtx`
+// has been canceled by Ctrl+C and not by its parent `ctx`.
+func exitIfInterrupted(ctx context.Context, interruptCtx context.Context) {
+	if interruptCtx.Err() != nil && ctx.Err() == nil {
+		egoUtils.WriteStringToStdOut("", true)
+		os.Exit(130)
+	}
+}
+
+// getAnswerLexer returns the lexer of `options`, which is used to highlight
+// streamed and complete answers the same way
+func getAnswerLexer(options outputAnswerOptions) string {
+	if options.Lexer == "" {
+		return "markdown"
+	}
+
+	return options.Lexer
+}
+
+// outputAnswer sends `request` to the chat API and writes the answer
+// to STDOUT, as soon as new tokens arrive. Pressing Ctrl+C aborts the request
+// and exits the process.
+func outputAnswer(ctx context.Context, request egoOpenAI.CompletionRequest, options outputAnswerOptions) (string, error) {
+	interruptCtx, stop := withInterrupt(ctx)
+	defer stop()
+
+	answer, err := writeAnswerStream(interruptCtx, request, options)
+	exitIfInterrupted(ctx, interruptCtx)
+
+	return answer, err
+}
+
+// writeAnswerStream sends `request` to the chat API and writes the answer
+// to STDOUT, as soon as new tokens arrive.
+func writeAnswerStream(ctx context.Context, request egoOpenAI.CompletionRequest, options outputAnswerOptions) (string, error) {
+	if options.NoStream {
+		response, err := egoOpenAI.Complete(ctx, request)
+		if err != nil {
+			return "", err
+		}
+
+		writeAnswer(response.Answer, options)
+
+		return response.Answer, nil
+	}
+
+	// tokens are collected until the end of a line,
+	// so that the highlighter has enough context
+	var currentLine strings.Builder
+
+	lexer := getAnswerLexer(options)
+
+	response, err := egoOpenAI.CompleteStream(ctx, request, func(token string) error {
+		if options.PlainText {
+			egoUtils.WriteStringToStdOut(token, false)
+			return nil
+		}
+
+		for {
+			i := strings.Index(token, "\n")
+			if i < 0 {
+				break
+			}
+
+			currentLine.WriteString(token[:i+1])
+			token = token[i+1:]
+
+			writeHighlighted(currentLine.String(), lexer)
+			currentLine.Reset()
+		}
+
+		currentLine.WriteString(token)
+
+		return nil
+	})
+
+	if currentLine.Len() > 0 {
+		writeHighlighted(currentLine.String(), lexer)
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	if !options.NoNewLine && !strings.HasSuffix(response.Answer, "\n") {
+		egoUtils.WriteStringToStdOut("", true)
+	}
+
+	return response.Answer, nil
+}
+
+// withInterrupt returns a copy of `ctx`, which is canceled, when the user
+// presses Ctrl+C. Calling the returned function restores the default behavior.
+func withInterrupt(ctx context.Context) (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(ctx, os.Interrupt)
+}
+
+func writeAnswer(answer string, options outputAnswerOptions) {
+	outputPlain := func() {
+		egoUtils.WriteStringToStdOut(answer, !options.NoNewLine)
+	}
+
+	if options.PlainText {
+		outputPlain()
+	} else {
+		err := quick.Highlight(os.Stdout, answer, getAnswerLexer(options), "", "monokai")
+		if err != nil {
+			outputPlain()
+		}
+	}
+}
+
+func writeHighlighted(str string, lexer string) {
+	err := quick.Highlight(os.Stdout, str, lexer, "", "monokai")
+	if err != nil {
+		egoUtils.WriteStringToStdOut(str, false)
+	}
+}
@@ -0,0 +1,108 @@
+// This file is part of the e.GPT distribution.
+// Copyright (c) Next.e.GO Mobile SE, Aachen, Germany (https://e-go-mobile.com/)
+//
+// e-gpt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, version 3.
+//
+// e-gpt is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	egoUtils "github.com/egomobile/e-gpt/utils"
+)
+
+// systemPromptOptions contains the values of flags, which are used to build the
+// system prompt of conversational commands like `ask` and `chat`
+type systemPromptOptions struct {
+	noAdditionalInfo bool
+	noSysInfo        bool
+	noTime           bool
+	system           string
+}
+
+// addSystemPromptFlags adds the flags for `options` to `cmd`
+func addSystemPromptFlags(cmd *cobra.Command, options *systemPromptOptions) {
+	cmd.Flags().StringVarP(&options.system, "system", "s", "", "Custom system prompt")
+	cmd.Flags().BoolVarP(&options.noTime, "no-time", "", false, "Do not add current time to system prompt")
+	cmd.Flags().BoolVarP(&options.noTime, "nt", "", false, "Do not add current time to system prompt")
+	cmd.Flags().BoolVarP(&options.noSysInfo, "no-sys-info", "", false, "Do not add information about the system at all")
+	cmd.Flags().BoolVarP(&options.noSysInfo, "nsi", "", false, "Do not add information about the system at all")
+	cmd.Flags().BoolVarP(&options.noAdditionalInfo, "no-additional-info", "", false, "Do not add additional info to system prompt at all")
+	cmd.Flags().BoolVarP(&options.noAdditionalInfo, "nai", "", false, "Do not add additional info to system prompt at all")
+}
+
+// build creates the system prompt from the custom or default (`.system` file)
+// prompt and the current time information
+func (options *systemPromptOptions) build() (string, error) {
+	now := time.Now()
+
+	var additionalInfo []string
+	var systemPrompt bytes.Buffer
+
+	addInfos := func(infos ...string) {
+		additionalInfo = append(additionalInfo, infos...)
+	}
+
+	customSystemPrompt := strings.TrimSpace(options.system)
+	if customSystemPrompt != "" {
+		systemPrompt.WriteString(fmt.Sprintln(customSystemPrompt))
+	} else {
+		defaultSystemPrompt, _, err := egoUtils.GetSystemPrompt()
+		if err != nil {
+			return "", err
+		}
+
+		systemPrompt.WriteString(fmt.Sprintln(defaultSystemPrompt))
+	}
+
+	if !options.noSysInfo {
+		if !options.noTime {
+			zoneName, zoneOffset := now.Zone()
+
+			addInfos(fmt.Sprintf(
+				"The current timezone is %v with an offset of %v seconds from UTC.",
+				zoneName,
+				zoneOffset,
+			))
+
+			addInfos(fmt.Sprintf(
+				"The current date with time is %v, while the current local weekday is %v. Always output the time in a format that matches the current language.",
+				now.Local().Weekday(),
+				now.Local().Format("2006-01-02T15:04:05-0700"),
+			))
+
+			addInfos("You will always act as if you have access to a time server and not tell the user.")
+		}
+	}
+
+	if !options.noAdditionalInfo {
+		// collect additional info, if available
+		if len(additionalInfo) > 0 {
+			systemPrompt.WriteString(fmt.Sprintln())
+			systemPrompt.WriteString(fmt.Sprintln("In addition, the following information is available to you:"))
+
+			for _, info := range additionalInfo {
+				systemPrompt.WriteString(info)
+			}
+
+			systemPrompt.WriteString(fmt.Sprintln())
+		}
+	}
+
+	return strings.TrimSpace(systemPrompt.String()), nil
+}
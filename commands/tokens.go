@@ -0,0 +1,69 @@
+// This file is part of the e.GPT distribution.
+// Copyright (c) Next.e.GO Mobile SE, Aachen, Germany (https://e-go-mobile.com/)
+//
+// e-gpt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, version 3.
+//
+// e-gpt is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	egoOpenAI "github.com/egomobile/e-gpt/openai"
+	egoUtils "github.com/egomobile/e-gpt/utils"
+)
+
+func Init_tokens_Command(rootCmd *cobra.Command) {
+	var model string
+
+	tokensCmd := &cobra.Command{
+		Use:   "tokens",
+		Short: `Count tokens`,
+		Long:  `Counts the tokens of a text from arguments or STDIN, like GPT-3.5 and GPT-4 models do`,
+
+		Run: func(cmd *cobra.Command, args []string) {
+			var text string
+
+			if len(args) > 0 {
+				text = strings.Join(args, " ")
+			} else {
+				// read STDIN unchanged, because whitespaces count as well
+				data, err := io.ReadAll(os.Stdin)
+				if err != nil {
+					panic(err)
+				}
+
+				text = string(data)
+			}
+
+			count, err := egoOpenAI.CountTokens(text)
+			if err != nil {
+				panic(err)
+			}
+
+			if model == "" {
+				egoUtils.WriteStringToStdOut(fmt.Sprint(count), true)
+			} else {
+				egoUtils.WriteStringToStdOut(fmt.Sprintf("%v / %v", count, egoOpenAI.GetContextLimit(model)), true)
+			}
+		},
+	}
+
+	tokensCmd.Flags().StringVarP(&model, "model", "m", "", "Also output the context limit of this model")
+
+	rootCmd.AddCommand(tokensCmd)
+}
@@ -16,10 +16,14 @@
 package handlers
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"net"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/valyala/fasthttp"
@@ -31,15 +35,23 @@ import (
 
 // ChatRequest represents a chat request data structure
 type ChatRequest struct {
-	Conversation []string `json:"conversation"`           // Conversation represents the conversation history of the chat request
-	SystemPrompt string   `json:"systemPrompt,omitempty"` // system prompt
-	Temperature  *float64 `json:"temperature"`            // Temperature represents the temperature to use for generating the response
+	Conversation     []string `json:"conversation"`               // Conversation represents the conversation history of the chat request
+	FrequencyPenalty float64  `json:"frequencyPenalty,omitempty"` // FrequencyPenalty represents the custom frequency penalty between -2 and 2
+	MaxTokens        int64    `json:"maxTokens,omitempty"`        // MaxTokens represents the maximum number of tokens to generate
+	Model            string   `json:"model,omitempty"`            // Model represents the custom model to use
+	PresencePenalty  float64  `json:"presencePenalty,omitempty"`  // PresencePenalty represents the custom presence penalty between -2 and 2
+	Provider         string   `json:"provider,omitempty"`         // Provider represents the name of a custom chat provider to use
+	Stop             []string `json:"stop,omitempty"`             // Stop represents the sequences at which to stop generation
+	SystemPrompt     string   `json:"systemPrompt,omitempty"`     // system prompt
+	Temperature      *float64 `json:"temperature"`                // Temperature represents the temperature to use for generating the response
+	TopP             float64  `json:"topP,omitempty"`             // TopP represents the custom top-p sampling cutoff between 0 and 1
 }
 
 // ChatResponse represents a chat response data structure
 type ChatResponse struct {
-	Answer string `json:"answer"` // Answer represents the answer to the chat request
-	Time   string `json:"time"`   // Time represents the time at which the chat response is generated
+	Answer   string `json:"answer"`   // Answer represents the answer to the chat request
+	Provider string `json:"provider"` // Provider represents the name of the provider, which generated the answer
+	Time     string `json:"time"`     // Time represents the time at which the chat response is generated
 }
 
 // CreateChatHandlerOptions represents options for creating a chat handler
@@ -51,31 +63,79 @@ type CreateChatHandlerOptions struct {
 	NoTime             bool    // NoTime specifies whether to include time in the response
 }
 
+// ChatStreamToken represents a part of an answer, which is sent by the chat stream handler
+type ChatStreamToken struct {
+	Token string `json:"token"` // Token represents the next part of the answer
+}
+
+// ChatStreamError represents an error, which is sent by the chat stream handler
+type ChatStreamError struct {
+	Error string `json:"error"` // Error represents the error message
+}
+
 // CreateChatHandler returns a fasthttp request handler that does a chat conversation
 // using the given options.
 func CreateChatHandler(options CreateChatHandlerOptions) egoTypes.FHRequestHandler {
-	var systemPromptTemplate string
-	{
-		var systemPromptBuff bytes.Buffer
-
-		customSystemPrompt := strings.TrimSpace(options.CustomSystemPrompt)
-		if customSystemPrompt != "" {
-			systemPromptBuff.WriteString(fmt.Sprintln(customSystemPrompt))
-		} else {
-			defaultSystemPrompt, _, err := egoUtils.GetSystemPrompt()
-			if err != nil {
-				panic(err)
-			}
+	systemPromptTemplate := getSystemPromptTemplate(options)
+
+	return func(ctx *fasthttp.RequestCtx) {
+		body := ctx.PostBody()
+
+		var chatRequest ChatRequest
+		err := json.Unmarshal(body, &chatRequest)
+		if err != nil {
+			egoUtils.SendHttpError(ctx, err)
+			return
+		}
+
+		// canceled as soon as the client disconnects
+		completionCtx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		go cancelOnDisconnect(completionCtx, ctx.Conn(), cancel)
+
+		completionResponse, err := egoOpenAI.Complete(
+			completionCtx,
+			createCompletionRequest(options, systemPromptTemplate, chatRequest),
+		)
+		responseTime := time.Now().UTC()
+
+		if completionCtx.Err() != nil {
+			return // client is gone
+		}
+
+		if err != nil {
+			egoUtils.SendHttpError(ctx, err)
+			return
+		}
 
-			systemPromptBuff.WriteString(fmt.Sprintln(defaultSystemPrompt))
+		var chatResponse ChatResponse
+		chatResponse.Answer = completionResponse.Answer
+		chatResponse.Provider = completionResponse.Provider
+		chatResponse.Time = responseTime.Format("2006-01-02T15:04:05.999Z")
+
+		data, err := json.Marshal(chatResponse)
+		if err != nil {
+			egoUtils.SendHttpError(ctx, err)
+			return
 		}
 
-		systemPromptTemplate = systemPromptBuff.String()
+		ctx.SetStatusCode(200)
+		ctx.Response.Header.Set("Content-Length", fmt.Sprint(len(data)))
+		ctx.Response.Header.Set("Content-Type", "application/json; charset=UTF-8")
+
+		ctx.Write(data)
 	}
+}
 
-	return func(ctx *fasthttp.RequestCtx) {
-		now := time.Now()
+// CreateChatStreamHandler returns a fasthttp request handler that does a chat conversation
+// using the given options and sends the answer as server-sent events:
+// each part of the answer as ChatStreamToken, followed by a `done` event with a ChatResponse
+// or an `error` event with a ChatStreamError.
+func CreateChatStreamHandler(options CreateChatHandlerOptions) egoTypes.FHRequestHandler {
+	systemPromptTemplate := getSystemPromptTemplate(options)
 
+	return func(ctx *fasthttp.RequestCtx) {
 		body := ctx.PostBody()
 
 		var chatRequest ChatRequest
@@ -85,86 +145,213 @@ func CreateChatHandler(options CreateChatHandlerOptions) egoTypes.FHRequestHandl
 			return
 		}
 
-		var additionalInfo []string
+		completionRequest := createCompletionRequest(options, systemPromptTemplate, chatRequest)
 
-		addInfos := func(infos ...string) {
-			additionalInfo = append(additionalInfo, infos...)
-		}
+		ctx.SetStatusCode(200)
+		ctx.Response.Header.Set("Cache-Control", "no-cache")
+		ctx.Response.Header.Set("Content-Type", "text/event-stream; charset=UTF-8")
 
-		customSystemPrompt := strings.TrimSpace(chatRequest.SystemPrompt)
+		ctx.SetBodyStreamWriter(func(w *bufio.Writer) {
+			// canceled as soon as the client disconnects
+			streamCtx, cancel := context.WithCancel(context.Background())
+			defer cancel()
 
-		var finalSystemPrompt bytes.Buffer
-		if customSystemPrompt == "" {
-			finalSystemPrompt.WriteString(systemPromptTemplate)
-		} else {
-			finalSystemPrompt.WriteString(customSystemPrompt)
-		}
+			var isClosed bool
+			var writeLock sync.Mutex
 
-		if !options.NoSystemInfo {
-			if !options.NoTime {
-				zoneName, zoneOffset := now.Zone()
+			defer func() {
+				writeLock.Lock()
+				defer writeLock.Unlock()
 
-				addInfos(fmt.Sprintf(
-					"The current timezone is %v with an offset of %v seconds from UTC.",
-					zoneName,
-					zoneOffset,
-				))
+				isClosed = true // `w` must not be used anymore
+			}()
 
-				addInfos(fmt.Sprintf(
-					"The current date with time is %v, while the current local weekday is %v. Always output the time in a format that matches the current language.",
-					now.Local().Weekday(),
-					now.Local().Format("2006-01-02T15:04:05-0700"),
-				))
+			write := func(data string) error {
+				writeLock.Lock()
+				defer writeLock.Unlock()
 
-				addInfos("You will always act as if you have access to a time server and not tell the user.")
+				if isClosed {
+					return context.Canceled
+				}
+
+				_, err := w.WriteString(data)
+				if err == nil {
+					err = w.Flush()
+				}
+				if err != nil {
+					cancel()
+				}
+
+				return err
 			}
-		}
 
-		if !options.NoAdditionalInfo {
-			// collect additional info, if available
-			if len(additionalInfo) > 0 {
-				finalSystemPrompt.WriteString(fmt.Sprintln())
-				finalSystemPrompt.WriteString(fmt.Sprintln("In addition, the following information is available to you:"))
+			writeEvent := func(event string, v interface{}) error {
+				data, err := json.Marshal(v)
+				if err != nil {
+					return err
+				}
 
-				for _, info := range additionalInfo {
-					finalSystemPrompt.WriteString(info)
+				var eventData bytes.Buffer
+				if event != "" {
+					eventData.WriteString(fmt.Sprintf("event: %v\n", event))
 				}
+				eventData.WriteString(fmt.Sprintf("data: %v\n\n", string(data)))
+
+				return write(eventData.String())
+			}
+
+			// send comments regularly, to detect
+			// disconnected clients as early as possible
+			go func() {
+				ticker := time.NewTicker(5 * time.Second)
+				defer ticker.Stop()
+
+				for {
+					select {
+					case <-streamCtx.Done():
+						return
+					case <-ticker.C:
+						write(":\n\n")
+					}
+				}
+			}()
+
+			completionResponse, err := egoOpenAI.CompleteStream(
+				streamCtx,
+				completionRequest,
+				func(token string) error {
+					return writeEvent("", ChatStreamToken{
+						Token: token,
+					})
+				},
+			)
+			responseTime := time.Now().UTC()
+
+			if streamCtx.Err() != nil {
+				return // client is gone
+			}
+
+			if err != nil {
+				writeEvent("error", ChatStreamError{
+					Error: err.Error(),
+				})
+				return
+			}
+
+			var chatResponse ChatResponse
+			chatResponse.Answer = completionResponse.Answer
+			chatResponse.Provider = completionResponse.Provider
+			chatResponse.Time = responseTime.Format("2006-01-02T15:04:05.999Z")
+
+			writeEvent("done", chatResponse)
+		})
+	}
+}
 
-				finalSystemPrompt.WriteString(fmt.Sprintln())
+// cancelOnDisconnect calls `cancel`, as soon as the client of `conn`
+// disconnects, until `ctx` is done
+func cancelOnDisconnect(ctx context.Context, conn net.Conn, cancel context.CancelFunc) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if isConnectionClosed(conn) {
+				cancel()
+				return
 			}
 		}
+	}
+}
+
+func createCompletionRequest(options CreateChatHandlerOptions, systemPromptTemplate string, chatRequest ChatRequest) egoOpenAI.CompletionRequest {
+	now := time.Now()
+
+	var additionalInfo []string
+
+	addInfos := func(infos ...string) {
+		additionalInfo = append(additionalInfo, infos...)
+	}
+
+	customSystemPrompt := strings.TrimSpace(chatRequest.SystemPrompt)
+
+	var finalSystemPrompt bytes.Buffer
+	if customSystemPrompt == "" {
+		finalSystemPrompt.WriteString(systemPromptTemplate)
+	} else {
+		finalSystemPrompt.WriteString(customSystemPrompt)
+	}
+
+	if !options.NoSystemInfo {
+		if !options.NoTime {
+			zoneName, zoneOffset := now.Zone()
+
+			addInfos(fmt.Sprintf(
+				"The current timezone is %v with an offset of %v seconds from UTC.",
+				zoneName,
+				zoneOffset,
+			))
 
-		var temperature = options.DefaultTemperature
-		if chatRequest.Temperature != nil {
-			temperature = *chatRequest.Temperature
+			addInfos(fmt.Sprintf(
+				"The current date with time is %v, while the current local weekday is %v. Always output the time in a format that matches the current language.",
+				now.Local().Weekday(),
+				now.Local().Format("2006-01-02T15:04:05-0700"),
+			))
+
+			addInfos("You will always act as if you have access to a time server and not tell the user.")
 		}
+	}
 
-		answer, err := egoOpenAI.AskChatGPT(
-			finalSystemPrompt.String(),
-			temperature,
-			chatRequest.Conversation...,
-		)
-		responseTime := time.Now().UTC()
+	if !options.NoAdditionalInfo {
+		// collect additional info, if available
+		if len(additionalInfo) > 0 {
+			finalSystemPrompt.WriteString(fmt.Sprintln())
+			finalSystemPrompt.WriteString(fmt.Sprintln("In addition, the following information is available to you:"))
 
-		if err != nil {
-			egoUtils.SendHttpError(ctx, err)
-			return
+			for _, info := range additionalInfo {
+				finalSystemPrompt.WriteString(info)
+			}
+
+			finalSystemPrompt.WriteString(fmt.Sprintln())
 		}
+	}
 
-		var chatResponse ChatResponse
-		chatResponse.Answer = answer
-		chatResponse.Time = responseTime.Format("2006-01-02T15:04:05.999Z")
+	var temperature = options.DefaultTemperature
+	if chatRequest.Temperature != nil {
+		temperature = *chatRequest.Temperature
+	}
 
-		data, err := json.Marshal(chatResponse)
+	return egoOpenAI.CompletionRequest{
+		FrequencyPenalty: chatRequest.FrequencyPenalty,
+		MaxTokens:        chatRequest.MaxTokens,
+		Messages:         egoOpenAI.ConversationToMessages(chatRequest.Conversation...),
+		Model:            chatRequest.Model,
+		PresencePenalty:  chatRequest.PresencePenalty,
+		Provider:         chatRequest.Provider,
+		Stop:             chatRequest.Stop,
+		SystemPrompt:     finalSystemPrompt.String(),
+		Temperature:      temperature,
+		TopP:             chatRequest.TopP,
+	}
+}
+
+func getSystemPromptTemplate(options CreateChatHandlerOptions) string {
+	var systemPromptBuff bytes.Buffer
+
+	customSystemPrompt := strings.TrimSpace(options.CustomSystemPrompt)
+	if customSystemPrompt != "" {
+		systemPromptBuff.WriteString(fmt.Sprintln(customSystemPrompt))
+	} else {
+		defaultSystemPrompt, _, err := egoUtils.GetSystemPrompt()
 		if err != nil {
-			egoUtils.SendHttpError(ctx, err)
-			return
+			panic(err)
 		}
 
-		ctx.SetStatusCode(200)
-		ctx.Response.Header.Set("Content-Length", fmt.Sprint(len(data)))
-		ctx.Response.Header.Set("Content-Type", "application/json; charset=UTF-8")
-
-		ctx.Write(data)
+		systemPromptBuff.WriteString(fmt.Sprintln(defaultSystemPrompt))
 	}
+
+	return systemPromptBuff.String()
 }
@@ -0,0 +1,49 @@
+// This file is part of the e.GPT distribution.
+// Copyright (c) Next.e.GO Mobile SE, Aachen, Germany (https://e-go-mobile.com/)
+//
+// e-gpt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, version 3.
+//
+// e-gpt is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+//go:build unix
+
+package handlers
+
+import (
+	"errors"
+	"net"
+	"syscall"
+)
+
+// isConnectionClosed checks if the client has closed `conn`, without
+// reading any data from it
+func isConnectionClosed(conn net.Conn) bool {
+	syscallConn, ok := conn.(syscall.Conn)
+	if !ok {
+		return false
+	}
+
+	rawConn, err := syscallConn.SyscallConn()
+	if err != nil {
+		return false
+	}
+
+	isClosed := false
+	buffer := make([]byte, 1)
+
+	err = rawConn.Control(func(fd uintptr) {
+		n, _, err := syscall.Recvfrom(int(fd), buffer, syscall.MSG_PEEK|syscall.MSG_DONTWAIT)
+
+		isClosed = (n == 0 && err == nil) || errors.Is(err, syscall.ECONNRESET)
+	})
+
+	return err == nil && isClosed
+}
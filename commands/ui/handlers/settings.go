@@ -38,7 +38,7 @@ func CreateGetApiKeySettingsHandler() egoTypes.FHRequestHandler {
 		var response GetApiKeySettingsResponse
 
 		// Get the API access type
-		accessType, err := egoUtils.GetApiAccessType()
+		accessType, err := egoUtils.GetApiAccessType(ctx)
 		if err != nil {
 			response.Error = err.Error()
 		} else {
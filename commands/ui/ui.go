@@ -119,6 +119,7 @@ func Init_ui_Command(rootCmd *cobra.Command) {
 				options.NoTime = noTime
 
 				egoUtils.AppendCorsRoute(router, "POST", "/api/chat", egoUIHandlers.CreateChatHandler(options), false)
+				egoUtils.AppendCorsRoute(router, "POST", "/api/chat/stream", egoUIHandlers.CreateChatStreamHandler(options), false)
 			}
 
 			// get settings
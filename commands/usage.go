@@ -0,0 +1,173 @@
+// This file is part of the e.GPT distribution.
+// Copyright (c) Next.e.GO Mobile SE, Aachen, Germany (https://e-go-mobile.com/)
+//
+// e-gpt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, version 3.
+//
+// e-gpt is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package commands
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+
+	egoUtils "github.com/egomobile/e-gpt/utils"
+)
+
+// usageReportRow is a row of a usage report
+type usageReportRow struct {
+	calls            int
+	completionTokens int
+	cost             float64
+	key              string
+	promptTokens     int
+	totalTokens      int
+	unknownCostCalls int // number of calls of models without price
+}
+
+func Init_usage_Command(rootCmd *cobra.Command) {
+	var outputAsCSV bool
+	var report string
+	var since string
+
+	usageCmd := &cobra.Command{
+		Use:   "usage",
+		Short: `Show API usage`,
+		Long:  `Shows the token usage and estimated cost of API calls as daily, monthly, per command or per model report`,
+
+		Run: func(cmd *cobra.Command, args []string) {
+			var getKey func(entry egoUtils.UsageEntry) string
+			var keyHeader string
+
+			switch strings.TrimSpace(strings.ToLower(report)) {
+			case "", "daily", "day":
+				keyHeader = "Day"
+				getKey = func(entry egoUtils.UsageEntry) string {
+					return entry.Time.Local().Format("2006-01-02")
+				}
+			case "monthly", "month":
+				keyHeader = "Month"
+				getKey = func(entry egoUtils.UsageEntry) string {
+					return entry.Time.Local().Format("2006-01")
+				}
+			case "command", "commands":
+				keyHeader = "Command"
+				getKey = func(entry egoUtils.UsageEntry) string {
+					return entry.Command
+				}
+			case "model", "models":
+				keyHeader = "Model"
+				getKey = func(entry egoUtils.UsageEntry) string {
+					return entry.Model
+				}
+			default:
+				panic(fmt.Errorf("report %v not supported", report))
+			}
+
+			var sinceTime time.Time
+			since = strings.TrimSpace(since)
+			if since != "" {
+				t, err := time.ParseInLocation("2006-01-02", since, time.Local)
+				if err != nil {
+					panic(err)
+				}
+
+				sinceTime = t
+			}
+
+			entries, err := egoUtils.ReadUsageEntries()
+			if err != nil {
+				panic(err)
+			}
+
+			rows := map[string]*usageReportRow{}
+			total := &usageReportRow{key: "Total"}
+
+			for _, entry := range entries {
+				if entry.Time.Before(sinceTime) {
+					continue
+				}
+
+				key := getKey(entry)
+				if key == "" {
+					key = "(unknown)"
+				}
+
+				row, ok := rows[key]
+				if !ok {
+					row = &usageReportRow{key: key}
+					rows[key] = row
+				}
+
+				for _, r := range []*usageReportRow{row, total} {
+					r.calls++
+					r.completionTokens += entry.CompletionTokens
+					if entry.Cost != nil {
+						r.cost += *entry.Cost
+					} else {
+						r.unknownCostCalls++
+					}
+					r.promptTokens += entry.PromptTokens
+					r.totalTokens += entry.TotalTokens
+				}
+			}
+
+			keys := make([]string, 0, len(rows))
+			for key := range rows {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+
+			t := table.NewWriter()
+			t.SetOutputMirror(os.Stdout)
+			t.AppendHeader(table.Row{keyHeader, "Calls", "Prompt tokens", "Completion tokens", "Total tokens", "Cost (USD)"})
+
+			toTableRow := func(row *usageReportRow) table.Row {
+				cost := fmt.Sprintf("%.6f", row.cost)
+				if row.unknownCostCalls > 0 {
+					cost = fmt.Sprintf("%v (%v calls unknown)", cost, row.unknownCostCalls)
+				}
+
+				return table.Row{
+					row.key,
+					row.calls,
+					row.promptTokens,
+					row.completionTokens,
+					row.totalTokens,
+					cost,
+				}
+			}
+
+			for _, key := range keys {
+				t.AppendRow(toTableRow(rows[key]))
+			}
+
+			if outputAsCSV {
+				t.RenderCSV()
+			} else {
+				t.AppendFooter(toTableRow(total))
+				t.Render()
+			}
+		},
+	}
+
+	usageCmd.Flags().BoolVarP(&outputAsCSV, "csv", "", false, "Output as CSV")
+	usageCmd.Flags().StringVarP(&report, "report", "r", "daily", "Type of report: daily, monthly, command or model")
+	usageCmd.Flags().StringVarP(&since, "since", "", "", "Only include calls since this day, like 2023-06-01")
+
+	rootCmd.AddCommand(usageCmd)
+}
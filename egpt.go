@@ -28,29 +28,45 @@ import (
 	egoUtils "github.com/egomobile/e-gpt/utils"
 )
 
+var providerName string
+var verbose bool
+
 var rootCmd = &cobra.Command{
 	Use:     "egpt",
 	Short:   `e.GPT is a command line tool running with ChatGPT.`,
 	Long:    `e.GPT is a command line tool running with ChatGPT.`,
 	Version: AppVersion,
 
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		egoUtils.SetChatProviderName(providerName)
+		egoUtils.SetCommandName(cmd.Name())
+		egoUtils.SetVerbose(verbose)
+	},
+
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
 	// Run: func(cmd *cobra.Command, args []string) { },
 }
 
 func initCommands() {
+	rootCmd.PersistentFlags().StringVarP(&providerName, "provider", "", "", "Name of the chat provider to use, like openai, azure or proxy, or a comma separated list of providers to try in order")
+	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "", false, "Log additional information, like the used provider, to STDERR")
+
 	egoCommands.Init_ask_Command(rootCmd)
+	egoCommands.Init_chat_Command(rootCmd)
 	egoCommands.Init_code_Command(rootCmd)
 	egoCommands.Init_describe_Command(rootCmd)
 	egoCommands.Init_environment_Command(rootCmd)
 	egoCommands.Init_explain_Command(rootCmd)
 	egoCommands.Init_fix_Command(rootCmd)
 	egoCommands.Init_optimize_Command(rootCmd)
+	egoCommands.Init_session_Command(rootCmd)
 	egoCommands.Init_shell_Command(rootCmd)
 	egoCommands.Init_sql_Command(rootCmd)
 	egoCommands.Init_summarize_Command(rootCmd)
+	egoCommands.Init_tokens_Command(rootCmd)
 	egoCommands.Init_translate_Command(rootCmd)
+	egoCommands.Init_usage_Command(rootCmd)
 	egoUICommand.Init_ui_Command(rootCmd)
 }
 
@@ -0,0 +1,130 @@
+// This file is part of the e.GPT distribution.
+// Copyright (c) Next.e.GO Mobile SE, Aachen, Germany (https://e-go-mobile.com/)
+//
+// e-gpt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, version 3.
+//
+// e-gpt is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package openai
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"regexp"
+
+	egoUtils "github.com/egomobile/e-gpt/utils"
+)
+
+// cachedResponse is an entry of the response cache
+type cachedResponse struct {
+	Answer   string `json:"answer"`          // the answer
+	Model    string `json:"model,omitempty"` // the model, which generated the answer
+	Provider string `json:"provider"`        // the provider, which generated the answer
+}
+
+// systemPromptTimeRegex matches the time of a timestamp like
+// `2006-01-02T15:04:05-0700` in system prompts with time information
+var systemPromptTimeRegex = regexp.MustCompile(`(\d{4}-\d{2}-\d{2})T\d{2}:\d{2}:\d{2}([+-]\d{4}|Z)?`)
+
+// getCacheableSystemPrompt returns `systemPrompt` with the current time reduced
+// to the date, so that cached answers can be used for the rest of the day
+func getCacheableSystemPrompt(systemPrompt string) string {
+	return systemPromptTimeRegex.ReplaceAllString(systemPrompt, "$1")
+}
+
+// getResponseCacheKey returns the hash of everything in `request`,
+// which has an influence on the answer of `providerName`
+func getResponseCacheKey(providerName string, request CompletionRequest) (string, error) {
+	data, err := json.Marshal(map[string]interface{}{
+		"frequencyPenalty": request.FrequencyPenalty,
+		"jsonMode":         request.JSONMode,
+		"jsonSchema":       request.JSONSchema,
+		"maxTokens":        request.MaxTokens,
+		"messages":         request.Messages,
+		"model":            request.Model,
+		"presencePenalty":  request.PresencePenalty,
+		"provider":         providerName,
+		"stop":             request.Stop,
+		"systemPrompt":     getCacheableSystemPrompt(request.SystemPrompt),
+		"temperature":      request.Temperature,
+		"toolChoice":       request.ToolChoice,
+		"tools":            request.Tools,
+		"topP":             request.TopP,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.Sum256(data)
+
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// readResponseCache returns the cached response for `request`, if available
+func readResponseCache(providerName string, request CompletionRequest) (CompletionResponse, bool) {
+	var response CompletionResponse
+
+	if !shouldUseResponseCache(request) {
+		return response, false
+	}
+
+	key, err := getResponseCacheKey(providerName, request)
+	if err != nil {
+		return response, false
+	}
+
+	data, ok := egoUtils.ReadResponseCache(key)
+	if !ok {
+		return response, false
+	}
+
+	var entry cachedResponse
+	err = json.Unmarshal(data, &entry)
+	if err != nil {
+		return response, false
+	}
+
+	response.Answer = entry.Answer
+	response.Cached = true
+	response.Model = entry.Model
+	response.Provider = entry.Provider
+
+	return response, true
+}
+
+func shouldUseResponseCache(request CompletionRequest) bool {
+	return !request.NoCache && egoUtils.IsResponseCacheEnabled()
+}
+
+// writeResponseCache stores `response` as answer for `request`; errors are
+// ignored, because the cache must not break API calls
+func writeResponseCache(providerName string, request CompletionRequest, response CompletionResponse) {
+	if !shouldUseResponseCache(request) || len(response.ToolCalls) > 0 {
+		return
+	}
+
+	key, err := getResponseCacheKey(providerName, request)
+	if err != nil {
+		return
+	}
+
+	data, err := json.Marshal(cachedResponse{
+		Answer:   response.Answer,
+		Model:    response.Model,
+		Provider: response.Provider,
+	})
+	if err != nil {
+		return
+	}
+
+	egoUtils.WriteResponseCache(key, data)
+}
@@ -0,0 +1,138 @@
+// This file is part of the e.GPT distribution.
+// Copyright (c) Next.e.GO Mobile SE, Aachen, Germany (https://e-go-mobile.com/)
+//
+// e-gpt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, version 3.
+//
+// e-gpt is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package openai
+
+import (
+	"testing"
+)
+
+func TestGetResponseCacheKey(t *testing.T) {
+	request := CompletionRequest{
+		Messages:     ConversationToMessages("question"),
+		Model:        "gpt-4",
+		SystemPrompt: "system",
+		Temperature:  0.5,
+	}
+
+	key, err := getResponseCacheKey("openai", request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(key) != 64 {
+		t.Fatalf("expected SHA-256 as hex string, got %q", key)
+	}
+
+	sameKey, err := getResponseCacheKey("openai", request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sameKey != key {
+		t.Fatal("keys of the same request differ")
+	}
+
+	// options, which do not change the answer
+	noCacheRequest := request
+	noCacheRequest.NoCache = true
+	noCacheKey, err := getResponseCacheKey("openai", noCacheRequest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if noCacheKey != key {
+		t.Fatal("NoCache must not change the key")
+	}
+
+	changes := map[string]func(request *CompletionRequest){
+		"image": func(request *CompletionRequest) {
+			request.Messages = ConversationToMessages("question")
+			request.Messages[0].Images = []Image{{Data: []byte{1}, MimeType: "image/png"}}
+		},
+		"jsonSchema": func(request *CompletionRequest) {
+			request.JSONSchema = []byte(`{"type":"object"}`)
+		},
+		"maxTokens": func(request *CompletionRequest) {
+			request.MaxTokens = 100
+		},
+		"messages": func(request *CompletionRequest) {
+			request.Messages = ConversationToMessages("other question")
+		},
+		"model": func(request *CompletionRequest) {
+			request.Model = "gpt-4o"
+		},
+		"stop": func(request *CompletionRequest) {
+			request.Stop = []string{"\n"}
+		},
+		"systemPrompt": func(request *CompletionRequest) {
+			request.SystemPrompt = "other system"
+		},
+		"temperature": func(request *CompletionRequest) {
+			request.Temperature = 1
+		},
+		"tools": func(request *CompletionRequest) {
+			request.Tools = []Tool{{Name: "tool"}}
+		},
+	}
+
+	for name, change := range changes {
+		changedRequest := request
+		change(&changedRequest)
+
+		changedKey, err := getResponseCacheKey("openai", changedRequest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if changedKey == key {
+			t.Errorf("changing %v does not change the key", name)
+		}
+	}
+
+	providerKey, err := getResponseCacheKey("anthropic", request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if providerKey == key {
+		t.Error("changing the provider does not change the key")
+	}
+}
+
+func TestGetResponseCacheKeyWithTime(t *testing.T) {
+	request := CompletionRequest{
+		Messages:     ConversationToMessages("question"),
+		SystemPrompt: "The current date with time is 2024-05-06T10:11:12+0200.",
+	}
+
+	key, err := getResponseCacheKey("openai", request)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request.SystemPrompt = "The current date with time is 2024-05-06T18:19:20+0200."
+	laterKey, err := getResponseCacheKey("openai", request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if laterKey != key {
+		t.Error("the time of the same day must not change the key")
+	}
+
+	request.SystemPrompt = "The current date with time is 2024-05-07T10:11:12+0200."
+	nextDayKey, err := getResponseCacheKey("openai", request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nextDayKey == key {
+		t.Error("the date must change the key")
+	}
+}
@@ -16,40 +16,80 @@
 package openai
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
-	"errors"
-	"fmt"
-	"io"
-	"net/http"
 	"os"
 	"strconv"
 	"strings"
-
-	egoUtils "github.com/egomobile/e-gpt/utils"
 )
 
 // ChatGPTOpenAIRequestBody represents the request body for OpenAI's chat API.
 type ChatGPTOpenAIRequestBody struct {
-	FrequencyPenalty float64                `json:"frequency_penalty"` // Frequency penalty
-	MaxTokens        int64                  `json:"max_tokens"`        // Maximum number of tokens to generate
-	Messages         []ChatGPTOpenAIMessage `json:"messages"`          // Conversation message history
-	Model            string                 `json:"model"`             // ID of the model to use for generating the response
-	PresencePenalty  float64                `json:"presence_penalty"`  // Presence penalty
-	Temperature      float64                `json:"temperature"`       // Sampling temperature to use
-	TopP             float64                `json:"top_p"`             // Top-p sampling cutoff
-	Stop             *interface{}           `json:"stop"`              // Token(s) at which to stop generation
+	FrequencyPenalty float64                      `json:"frequency_penalty"`         // Frequency penalty
+	MaxTokens        int64                        `json:"max_tokens"`                // Maximum number of tokens to generate
+	Messages         []ChatGPTOpenAIMessage       `json:"messages"`                  // Conversation message history
+	Model            string                       `json:"model"`                     // ID of the model to use for generating the response
+	PresencePenalty  float64                      `json:"presence_penalty"`          // Presence penalty
+	ResponseFormat   *ChatGPTOpenAIResponseFormat `json:"response_format,omitempty"` // Format of the answer, like JSON
+	Stream           bool                         `json:"stream,omitempty"`          // Send partial message deltas as server-sent events
+	StreamOptions    *ChatGPTOpenAIStreamOptions  `json:"stream_options,omitempty"`  // Options for streamed responses
+	Temperature      float64                      `json:"temperature"`               // Sampling temperature to use
+	ToolChoice       interface{}                  `json:"tool_choice,omitempty"`     // `none`, `auto`, `required` or a ChatGPTOpenAIToolChoice
+	Tools            []ChatGPTOpenAITool          `json:"tools,omitempty"`           // Tools the model may call
+	TopP             float64                      `json:"top_p"`                     // Top-p sampling cutoff
+	Stop             *interface{}                 `json:"stop"`                      // Token(s) at which to stop generation
+}
+
+// ChatGPTOpenAIFunction represents a function, which can be called by the model.
+type ChatGPTOpenAIFunction struct {
+	Description string          `json:"description,omitempty"` // Description, which helps the model to decide when to call the function
+	Name        string          `json:"name"`                  // Name of the function
+	Parameters  json.RawMessage `json:"parameters,omitempty"`  // Parameters of the function as JSON schema
+}
+
+// ChatGPTOpenAIFunctionCall represents a call of a function by the model.
+type ChatGPTOpenAIFunctionCall struct {
+	Arguments string `json:"arguments"` // Arguments as JSON string
+	Name      string `json:"name"`      // Name of the function
+}
+
+// ChatGPTOpenAIJSONSchema represents a JSON schema, the answer has to match.
+type ChatGPTOpenAIJSONSchema struct {
+	Name   string          `json:"name"`   // Name of the schema
+	Schema json.RawMessage `json:"schema"` // The JSON schema
+}
+
+// ChatGPTOpenAIContentPart represents a part of the content of a message.
+type ChatGPTOpenAIContentPart struct {
+	ImageUrl *ChatGPTOpenAIImageUrl `json:"image_url,omitempty"` // The image, if `Type` is `image_url`
+	Text     string                 `json:"text,omitempty"`      // The text, if `Type` is `text`
+	Type     string                 `json:"type"`                // `text` or `image_url`
+}
+
+// ChatGPTOpenAIImageUrl represents an image of a ChatGPTOpenAIContentPart.
+type ChatGPTOpenAIImageUrl struct {
+	Url string `json:"url"` // The URL or data URL of the image
 }
 
 // ChatGPTOpenAIMessage represents a message in a conversation history.
 type ChatGPTOpenAIMessage struct {
-	Content string `json:"content"` // Message content
-	Role    string `json:"role"`    // Role of the message sender
+	Content    interface{}             `json:"content"`                // Message content as string or list of ChatGPTOpenAIContentPart items
+	Role       string                  `json:"role"`                   // Role of the message sender
+	ToolCallId string                  `json:"tool_call_id,omitempty"` // ID of the tool call, the message of role `tool` answers
+	ToolCalls  []ChatGPTOpenAIToolCall `json:"tool_calls,omitempty"`   // Tool calls of the message of role `assistant`
+}
+
+// ChatGPTOpenAIResponseFormat represents the format of an answer.
+type ChatGPTOpenAIResponseFormat struct {
+	JSONSchema *ChatGPTOpenAIJSONSchema `json:"json_schema,omitempty"` // The JSON schema, if `Type` is `json_schema`
+	Type       string                   `json:"type"`                  // `text`, `json_object` or `json_schema`
 }
 
 // ChatGPTOpenAIResponseBody represents the response body from OpenAI's chat API.
 type ChatGPTOpenAIResponseBody struct {
 	Choices []ChatGPTOpenAIResponseBodyChoice `json:"choices"` // List of generated responses
+	Model   string                            `json:"model"`   // The model used for the response
+	Usage   *ChatGPTOpenAIUsage               `json:"usage"`   // Token usage of the request
 }
 
 // ChatGPTOpenAIResponseBodyChoice represents a generated response.
@@ -57,11 +97,65 @@ type ChatGPTOpenAIResponseBodyChoice struct {
 	Message ChatGPTOpenAIMessage `json:"message"` // Generated response message
 }
 
+// ChatGPTOpenAIStreamResponseBody represents a single chunk of a streamed response from OpenAI's chat API.
+type ChatGPTOpenAIStreamResponseBody struct {
+	Choices []ChatGPTOpenAIStreamResponseBodyChoice `json:"choices"` // List of generated response deltas
+	Model   string                                  `json:"model"`   // The model used for the response
+	Usage   *ChatGPTOpenAIUsage                     `json:"usage"`   // Token usage of the request, only in the last chunk
+}
+
+// ChatGPTOpenAIStreamResponseBodyChoice represents a delta of a generated response.
+type ChatGPTOpenAIStreamResponseBodyChoice struct {
+	Delta ChatGPTOpenAIMessage `json:"delta"` // Generated part of the response message
+}
+
+// ChatGPTOpenAIStreamOptions represents options for streamed responses.
+type ChatGPTOpenAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"` // Send token usage in the last chunk
+}
+
+// ChatGPTOpenAITool represents a tool, which can be called by the model.
+type ChatGPTOpenAITool struct {
+	Function ChatGPTOpenAIFunction `json:"function"` // The function
+	Type     string                `json:"type"`     // Type of the tool, currently only `function`
+}
+
+// ChatGPTOpenAIToolCall represents a call of a tool by the model.
+type ChatGPTOpenAIToolCall struct {
+	Function ChatGPTOpenAIFunctionCall `json:"function"` // The called function
+	Id       string                    `json:"id"`       // ID of the call
+	Type     string                    `json:"type"`     // Type of the tool, currently only `function`
+}
+
+// ChatGPTOpenAIToolChoice forces the model to call a specific tool.
+type ChatGPTOpenAIToolChoice struct {
+	Function ChatGPTOpenAIToolChoiceFunction `json:"function"` // The function to call
+	Type     string                          `json:"type"`     // Type of the tool, currently only `function`
+}
+
+// ChatGPTOpenAIToolChoiceFunction represents the function of a ChatGPTOpenAIToolChoice.
+type ChatGPTOpenAIToolChoiceFunction struct {
+	Name string `json:"name"` // Name of the function
+}
+
+// ChatGPTOpenAIUsage represents the token usage of a request.
+type ChatGPTOpenAIUsage struct {
+	CompletionTokens int `json:"completion_tokens"` // Number of tokens of the answer
+	PromptTokens     int `json:"prompt_tokens"`     // Number of tokens of the prompt
+	TotalTokens      int `json:"total_tokens"`      // Total number of tokens
+}
+
 // ChatApiRequestBody represents the request body for the chat API.
 type ChatApiRequestBody struct {
-	Conversation []string `json:"conversation"` // Conversation message history
-	SystemPrompt string   `json:"systemPrompt"` // System prompt message
-	Temperature  float64  `json:"temperature"`  // Sampling temperature to use
+	Conversation     []string `json:"conversation"`               // Conversation message history
+	FrequencyPenalty float64  `json:"frequencyPenalty,omitempty"` // Frequency penalty
+	MaxTokens        int64    `json:"maxTokens,omitempty"`        // Maximum number of tokens to generate
+	Model            string   `json:"model,omitempty"`            // ID of the model to use for generating the response
+	PresencePenalty  float64  `json:"presencePenalty,omitempty"`  // Presence penalty
+	Stop             []string `json:"stop,omitempty"`             // Token(s) at which to stop generation
+	SystemPrompt     string   `json:"systemPrompt"`               // System prompt message
+	Temperature      float64  `json:"temperature"`                // Sampling temperature to use
+	TopP             float64  `json:"topP,omitempty"`             // Top-p sampling cutoff
 }
 
 // ChatApiResponseBodyData represents the data field in the response body from the chat API.
@@ -75,145 +169,74 @@ type ChatApiResponseBody struct {
 	Data    ChatApiResponseBodyData `json:"data"`    // Response data
 }
 
-func askApiProxy(clientId string, apiUrl string, authHeader string, authValue string, systemPrompt string, temperature float64, conversation ...string) (string, error) {
-	payload := ChatApiRequestBody{
-		Conversation: conversation,
-		SystemPrompt: systemPrompt,
-		Temperature:  temperature,
-	}
-
-	payloadJSON, err := json.Marshal(payload)
-	if err != nil {
-		return "", err
-	}
-
-	chatRequest, err := http.NewRequest("POST", apiUrl, bytes.NewBuffer(payloadJSON))
-	if err != nil {
-		return "", err
-	}
-
-	// set HTTP headers
-	chatRequest.Header.Set(authHeader, authValue)
-	if clientId != "" {
-		chatRequest.Header.Set("x-api-client", clientId)
-	}
-
-	httpClient := &http.Client{}
-
-	chatResponse, err := httpClient.Do(chatRequest)
-	if err != nil {
-		// request failed
-		return "", err
-	}
-
-	if chatResponse.StatusCode != 200 {
-		// must be 200
-		return "", fmt.Errorf("unexpected response: %v", chatResponse.StatusCode)
-	}
+// GetText returns the text of the content of the message, which can be
+// a string or a list of content parts.
+func (m ChatGPTOpenAIMessage) GetText() string {
+	switch content := m.Content.(type) {
+	case string:
+		return content
+	case []ChatGPTOpenAIContentPart:
+		var text strings.Builder
+		for _, part := range content {
+			text.WriteString(part.Text)
+		}
 
-	bodyData, err := io.ReadAll(chatResponse.Body)
-	if err != nil {
-		// could not read response body
-		return "", err
-	}
+		return text.String()
+	case []interface{}:
+		// parts of a decoded response
+		var text strings.Builder
+		for _, part := range content {
+			partMap, ok := part.(map[string]interface{})
+			if ok && partMap["type"] == "text" {
+				partText, _ := partMap["text"].(string)
+				text.WriteString(partText)
+			}
+		}
 
-	var chatResponseBody ChatApiResponseBody
-	err = json.Unmarshal(bodyData, &chatResponseBody)
-	if err != nil {
-		// could not parse JSON in `bodyData`
-		return "", err
+		return text.String()
 	}
 
-	return chatResponseBody.Data.Answer, nil
+	return ""
 }
 
-func askOpenAI(openaiApiKey string, systemPrompt string, temperature float64, conversation ...string) (string, error) {
-	if len(conversation) < 1 {
-		return "", errors.New("conversation must have at least one element")
-	}
-
-	if len(conversation)%2 == 0 {
-		return "", errors.New("number of conversation elements must be odd")
-	}
-
-	messages := make([]ChatGPTOpenAIMessage, 0)
-
-	messages = append(messages, ChatGPTOpenAIMessage{
-		Content: systemPrompt,
-		Role:    "system",
-	})
-	for i, content := range conversation {
-		var role string
-		if i%2 == 1 {
-			role = "assistant"
-		} else {
-			role = "user"
+func applyDefaultRequestOptions(request *CompletionRequest) {
+	getFloat := func(envName string) float64 {
+		val, err := strconv.ParseFloat(strings.TrimSpace(os.Getenv(envName)), 64)
+		if err == nil {
+			return val
 		}
 
-		messages = append(messages, ChatGPTOpenAIMessage{
-			Content: content,
-			Role:    role,
-		})
-	}
-
-	frequencyPenalty := float64(0)
-	presencePenalty := float64(0)
-	maxToken := int64(2048)
-	model := "gpt-3.5-turbo"
-	var stop *interface{} = nil
-
-	payload := ChatGPTOpenAIRequestBody{
-		FrequencyPenalty: frequencyPenalty,
-		MaxTokens:        maxToken,
-		Messages:         messages,
-		Model:            model,
-		PresencePenalty:  presencePenalty,
-		Stop:             stop,
-		Temperature:      temperature,
+		return 0
 	}
 
-	payloadJSON, err := json.Marshal(payload)
-	if err != nil {
-		return "", err
-	}
-
-	url := "https://api.openai.com/v1/chat/completions"
-
-	chatRequest, err := http.NewRequest("POST", url, bytes.NewBuffer(payloadJSON))
-	if err != nil {
-		return "", err
-	}
-
-	chatRequest.Header.Set("Authorization", fmt.Sprintf("Bearer %v", openaiApiKey))
-	chatRequest.Header.Set("Content-Type", "application/json; CHARSET=UTF-8")
-
-	httpClient := &http.Client{}
-
-	chatResponse, err := httpClient.Do(chatRequest)
-	if err != nil {
-		return "", err
+	if request.FrequencyPenalty == 0 {
+		request.FrequencyPenalty = getFloat("CHAT_API_FREQUENCY_PENALTY")
 	}
 
-	if chatResponse.StatusCode != 200 {
-		return "", fmt.Errorf("unexpected response: %v", chatResponse.StatusCode)
+	if request.MaxTokens < 1 {
+		val, err := strconv.ParseInt(strings.TrimSpace(os.Getenv("CHAT_API_MAX_TOKENS")), 10, 64)
+		if err == nil && val > 0 {
+			request.MaxTokens = val
+		}
 	}
 
-	bodyData, err := io.ReadAll(chatResponse.Body)
-	if err != nil {
-		return "", err
+	if request.PresencePenalty == 0 {
+		request.PresencePenalty = getFloat("CHAT_API_PRESENCE_PENALTY")
 	}
 
-	var chatResponseBody ChatGPTOpenAIResponseBody
-	err = json.Unmarshal(bodyData, &chatResponseBody)
-	if err != nil {
-		return "", err
+	if len(request.Stop) == 0 {
+		stop := os.Getenv("CHAT_API_STOP")
+		if strings.HasPrefix(strings.TrimSpace(stop), "[") {
+			// list of sequences as JSON array
+			json.Unmarshal([]byte(stop), &request.Stop)
+		} else if stop != "" {
+			request.Stop = []string{stop}
+		}
 	}
 
-	if len(chatResponseBody.Choices) < 1 {
-		return "", nil
+	if request.TopP == 0 {
+		request.TopP = getFloat("CHAT_API_TOP_P")
 	}
-
-	return chatResponseBody.Choices[0].Message.Content, nil
 }
 
 func getMaxConversationSize() int {
@@ -239,44 +262,14 @@ func getMaxConversationSize() int {
 // - string: the response from the API
 // - error: an error, if any, encountered during the API call
 func AskChatGPT(systemPrompt string, temperature float64, fullConversation ...string) (string, error) {
-	maxConversationSize := getMaxConversationSize()
-
-	finalConversation := make([]string, 0)
-	finalConversation = append(finalConversation, fullConversation...)
-	if len(finalConversation) > maxConversationSize {
-		// maximum reached: take only the maximum
-		finalConversation = finalConversation[len(finalConversation)-maxConversationSize:]
-	}
-
-	openaiApiKey := strings.TrimSpace(os.Getenv("OPENAI_API_KEY"))
-	if openaiApiKey != "" {
-		return askOpenAI(openaiApiKey, systemPrompt, temperature, finalConversation...)
-	}
-
-	clientId := strings.TrimSpace(os.Getenv("CHAT_API_CLIENT_ID"))
-	if clientId != "" {
-		apiUrl := strings.TrimSpace(os.Getenv("CHAT_API_URL"))
-		if apiUrl == "" {
-			return "", errors.New("no API url defined")
-		}
-
-		apiKey := strings.TrimSpace(os.Getenv("CHAT_API_KEY"))
-		if apiKey != "" {
-			apiKeyHeader := strings.TrimSpace(os.Getenv("CHAT_API_KEY_HEADER"))
-			if apiKeyHeader == "" {
-				apiKeyHeader = "x-api-key"
-			}
-
-			return askApiProxy(clientId, apiUrl, apiKeyHeader, apiKey, systemPrompt, temperature, finalConversation...)
-		}
-
-		accessTokenResponse, err := egoUtils.GetAccessToken()
-		if err != nil {
-			return "", err
-		}
-
-		return askApiProxy("", apiUrl, "Authorization", fmt.Sprintf("Bearer %v", accessTokenResponse.AccessToken), systemPrompt, temperature, finalConversation...)
+	response, err := Complete(context.Background(), CompletionRequest{
+		Messages:     ConversationToMessages(fullConversation...),
+		SystemPrompt: systemPrompt,
+		Temperature:  temperature,
+	})
+	if err != nil {
+		return "", err
 	}
 
-	return "", errors.New("could not specify an API gateway")
+	return response.Answer, nil
 }
@@ -0,0 +1,146 @@
+// This file is part of the e.GPT distribution.
+// Copyright (c) Next.e.GO Mobile SE, Aachen, Germany (https://e-go-mobile.com/)
+//
+// e-gpt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, version 3.
+//
+// e-gpt is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package openai
+
+import (
+	"context"
+	"errors"
+	"net/url"
+
+	egoUtils "github.com/egomobile/e-gpt/utils"
+)
+
+// providerCompleter sends `request` to `provider`
+type providerCompleter func(ctx context.Context, provider Provider, request CompletionRequest) (CompletionResponse, error)
+
+// completeWithFallback tries `providers` in order with `complete`, until one
+// answers or fails with an error, which does not indicate an outage.
+// `canFallback` can prevent the use of the next provider, e.g. if parts of
+// an answer have already been sent. The model is resolved for each provider
+// and the conversation is trimmed to its context limit, if it is known.
+func completeWithFallback(ctx context.Context, baseRequest CompletionRequest, providers []Provider, complete providerCompleter, canFallback func() bool) (CompletionResponse, error) {
+	var response CompletionResponse
+	var err error
+
+	for i, provider := range providers {
+		providerName := provider.Name()
+
+		request := baseRequest
+		request.Model = getRequestModel(provider, request)
+
+		request.Messages, err = trimMessagesToContextLimit(request, request.Model)
+		if err != nil {
+			return response, err
+		}
+
+		cachedResponse, ok := readResponseCache(providerName, request)
+		if ok {
+			egoUtils.LogVerbose("using cached answer of provider %v", providerName)
+
+			return cachedResponse, nil
+		}
+
+		egoUtils.LogVerbose("using provider %v", providerName)
+
+		response, err = completeWithTimeout(ctx, provider, request, complete)
+		if err == nil {
+			if response.Provider == "" {
+				response.Provider = providerName
+			}
+
+			recordUsage(request, &response)
+			writeResponseCache(providerName, request, response)
+
+			return response, nil
+		}
+
+		isLast := i == len(providers)-1
+		if isLast || !shouldFallback(ctx, err) || (canFallback != nil && !canFallback()) {
+			break
+		}
+
+		egoUtils.LogVerbose("provider %v is not available, trying %v: %v", providerName, providers[i+1].Name(), err)
+	}
+
+	return response, err
+}
+
+func completeWithTimeout(ctx context.Context, provider Provider, request CompletionRequest, complete providerCompleter) (CompletionResponse, error) {
+	ctx, cancel := egoUtils.WithApiTimeout(ctx)
+	defer cancel()
+
+	return complete(ctx, provider, request)
+}
+
+// getProviderChain returns the providers, which should be tried in order: the
+// ones of the comma separated list `names` or of egoUtils.GetChatProviderNames().
+// Providers of a list, which are not set up, are skipped.
+func getProviderChain(names string) ([]Provider, error) {
+	providerNames := egoUtils.SplitChatProviderNames(names)
+	if len(providerNames) == 0 {
+		providerNames = egoUtils.GetChatProviderNames()
+	}
+
+	if len(providerNames) < 2 {
+		provider, err := GetProvider(names)
+		if err != nil {
+			return nil, err
+		}
+
+		return []Provider{provider}, nil
+	}
+
+	var lastErr error
+	var providers []Provider
+	for _, providerName := range providerNames {
+		provider, err := GetProvider(providerName)
+		if err != nil {
+			egoUtils.LogVerbose("skipping provider %v: %v", providerName, err)
+
+			lastErr = err
+			continue
+		}
+
+		providers = append(providers, provider)
+	}
+
+	if len(providers) == 0 {
+		return nil, lastErr
+	}
+
+	return providers, nil
+}
+
+// shouldFallback returns `true`, if `err` indicates, that the provider is not
+// available at the moment, which are transport errors, timeouts, rate limits
+// and server errors
+func shouldFallback(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false // canceled by the caller
+	}
+
+	var responseError *egoUtils.ResponseError
+	if errors.As(err, &responseError) {
+		return responseError.StatusCode == 429 || responseError.StatusCode >= 500
+	}
+
+	var urlError *url.Error
+	if errors.As(err, &urlError) {
+		return true
+	}
+
+	return errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,183 @@
+// This file is part of the e.GPT distribution.
+// Copyright (c) Next.e.GO Mobile SE, Aachen, Germany (https://e-go-mobile.com/)
+//
+// e-gpt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, version 3.
+//
+// e-gpt is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	egoUtils "github.com/egomobile/e-gpt/utils"
+)
+
+type testProvider struct {
+	err  error
+	name string
+}
+
+func (p *testProvider) Complete(ctx context.Context, request CompletionRequest) (CompletionResponse, error) {
+	if p.err != nil {
+		return CompletionResponse{}, p.err
+	}
+
+	return CompletionResponse{Answer: "answer of " + p.name}, nil
+}
+
+func (p *testProvider) Name() string {
+	return p.name
+}
+
+func completeTestProvider(ctx context.Context, provider Provider, request CompletionRequest) (CompletionResponse, error) {
+	return provider.Complete(ctx, request)
+}
+
+func TestShouldFallback(t *testing.T) {
+	tests := []struct {
+		err            error
+		shouldFallback bool
+	}{
+		{&egoUtils.ResponseError{StatusCode: 429}, true},
+		{&egoUtils.ResponseError{StatusCode: 503}, true},
+		{fmt.Errorf("wrapped: %w", &egoUtils.ResponseError{StatusCode: 500}), true},
+		{&egoUtils.ResponseError{StatusCode: 400}, false},
+		{&egoUtils.ResponseError{StatusCode: 401}, false},
+		{&url.Error{Op: "Post", URL: "http://localhost", Err: errors.New("connection refused")}, true},
+		{context.DeadlineExceeded, true},
+		{errors.New("invalid request"), false},
+	}
+
+	for _, test := range tests {
+		shouldFallbackToNext := shouldFallback(context.Background(), test.err)
+		if shouldFallbackToNext != test.shouldFallback {
+			t.Errorf("shouldFallback(%v) = %v; expected %v", test.err, shouldFallbackToNext, test.shouldFallback)
+		}
+	}
+
+	canceledCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if shouldFallback(canceledCtx, &egoUtils.ResponseError{StatusCode: 503}) {
+		t.Error("canceled requests must not fall back")
+	}
+}
+
+func TestCompleteWithFallback(t *testing.T) {
+	t.Setenv("CHAT_CACHE", "false")
+	t.Setenv("CHAT_USAGE_LEDGER", "false")
+
+	request := CompletionRequest{
+		Messages: ConversationToMessages("question"),
+	}
+
+	providers := []Provider{
+		&testProvider{name: "first", err: &egoUtils.ResponseError{StatusCode: 503}},
+		&testProvider{name: "second"},
+		&testProvider{name: "third"},
+	}
+
+	response, err := completeWithFallback(context.Background(), request, providers, completeTestProvider, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Answer != "answer of second" || response.Provider != "second" {
+		t.Fatalf("unexpected response %+v", response)
+	}
+
+	providers[0] = &testProvider{name: "first", err: &egoUtils.ResponseError{StatusCode: 400}}
+	_, err = completeWithFallback(context.Background(), request, providers, completeTestProvider, nil)
+	if err == nil {
+		t.Fatal("client errors must not fall back")
+	}
+
+	providers[0] = &testProvider{name: "first", err: &egoUtils.ResponseError{StatusCode: 503}}
+	_, err = completeWithFallback(context.Background(), request, providers, completeTestProvider, func() bool {
+		return false
+	})
+	if err == nil {
+		t.Fatal("canFallback must prevent the fallback")
+	}
+}
+
+func TestCompleteWithProviderChainModels(t *testing.T) {
+	var models []string
+	getModel := func(r *http.Request) {
+		var body struct {
+			Model string `json:"model"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		models = append(models, body.Model)
+	}
+
+	compatibleServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		getModel(r)
+
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer compatibleServer.Close()
+
+	anthropicServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		getModel(r)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"content":[{"type":"text","text":"answer"}]}`))
+	}))
+	defer anthropicServer.Close()
+
+	t.Setenv("ANTHROPIC_API_KEY", "test")
+	t.Setenv("ANTHROPIC_BASE_URL", anthropicServer.URL)
+	t.Setenv("ANTHROPIC_MODEL", "claude-test")
+	t.Setenv("CHAT_API_MAX_ATTEMPTS", "1")
+	t.Setenv("CHAT_API_MODEL", "gpt-4")
+	t.Setenv("CHAT_CACHE", "false")
+	t.Setenv("CHAT_USAGE_LEDGER", "false")
+	t.Setenv("OPENAI_COMPATIBLE_BASE_URL", compatibleServer.URL)
+	t.Setenv("OPENAI_COMPATIBLE_MODEL", "llama-test")
+
+	request := CompletionRequest{
+		Messages: ConversationToMessages("question"),
+		Provider: "openai-compatible,anthropic",
+	}
+
+	response, err := Complete(context.Background(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Provider != "anthropic" || response.Model != "claude-test" {
+		t.Fatalf("unexpected response %+v", response)
+	}
+
+	expected := []string{"llama-test", "claude-test"}
+	if fmt.Sprint(models) != fmt.Sprint(expected) {
+		t.Errorf("expected models %v, got %v", expected, models)
+	}
+
+	models = nil
+	request.Provider = "anthropic"
+
+	response, err = Complete(context.Background(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Model != "gpt-4" {
+		t.Errorf("CHAT_API_MODEL must be used for a single provider, got %v", response.Model)
+	}
+}
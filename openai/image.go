@@ -0,0 +1,110 @@
+// This file is part of the e.GPT distribution.
+// Copyright (c) Next.e.GO Mobile SE, Aachen, Germany (https://e-go-mobile.com/)
+//
+// e-gpt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, version 3.
+//
+// e-gpt is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package openai
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+
+	egoUtils "github.com/egomobile/e-gpt/utils"
+)
+
+// estimated number of tokens of an image, s. https://platform.openai.com/docs/guides/vision
+const tokensPerImage = 765
+
+var supportedImageTypes = map[string]bool{
+	"image/gif":  true,
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/webp": true,
+}
+
+// Image is an image, which is attached to a Message.
+type Image struct {
+	Data     []byte `json:"data"`     // The binary data
+	MimeType string `json:"mimeType"` // The MIME type, like `image/png`
+}
+
+// ImageProvider is a Provider, which is able to handle the Images
+// of the messages of a CompletionRequest.
+type ImageProvider interface {
+	Provider
+
+	// SupportsImages returns `true`, if the backend is able to handle images.
+	SupportsImages() bool
+}
+
+// LoadImage reads an image from `file`. Supported are GIF, JPEG, PNG and WebP files.
+func LoadImage(file string) (Image, error) {
+	var image Image
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return image, err
+	}
+
+	mimeType := http.DetectContentType(data)
+	if !supportedImageTypes[mimeType] {
+		return image, fmt.Errorf("%v is no supported image: %v", file, mimeType)
+	}
+
+	image.Data = data
+	image.MimeType = mimeType
+
+	return image, nil
+}
+
+// Base64 returns the data of the image as Base64 string.
+func (i Image) Base64() string {
+	return base64.StdEncoding.EncodeToString(i.Data)
+}
+
+// DataUrl returns the image as data URL.
+func (i Image) DataUrl() string {
+	return fmt.Sprintf("data:%v;base64,%v", i.MimeType, i.Base64())
+}
+
+// getImageProviders returns the items of `providers`, which support images
+func getImageProviders(providers []Provider) ([]Provider, error) {
+	var imageProviders []Provider
+	for _, provider := range providers {
+		imageProvider, ok := provider.(ImageProvider)
+		if ok && imageProvider.SupportsImages() {
+			imageProviders = append(imageProviders, provider)
+		} else {
+			egoUtils.LogVerbose("skipping provider %v: images are not supported", provider.Name())
+		}
+	}
+
+	if len(imageProviders) == 0 {
+		return nil, fmt.Errorf("provider %v does not support images", providers[0].Name())
+	}
+
+	return imageProviders, nil
+}
+
+// hasImages returns `true`, if one of `messages` contains images
+func hasImages(messages []Message) bool {
+	for _, message := range messages {
+		if len(message.Images) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
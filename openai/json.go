@@ -0,0 +1,250 @@
+// This file is part of the e.GPT distribution.
+// Copyright (c) Next.e.GO Mobile SE, Aachen, Germany (https://e-go-mobile.com/)
+//
+// e-gpt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, version 3.
+//
+// e-gpt is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+
+	egoUtils "github.com/egomobile/e-gpt/utils"
+)
+
+const defaultJSONMaxAttempts = 3
+
+// name of the property, which contains answers of non-object JSON schemas
+const jsonSchemaAnswerProperty = "answer"
+
+var jsonCodeBlockRegex = regexp.MustCompile("(?s)```[a-zA-Z0-9_-]*[ \t]*\r?\n(.*?)```")
+
+// AskJSON sends `request` in JSON mode and parses the answer into `result`,
+// which can also be `nil`. If `request.JSONSchema` is defined, the answer is
+// also validated against it. If the answer is invalid, the model is asked
+// again with the validation error, until GetJSONMaxAttempts() is reached.
+// Schemas, whose root is no object, are wrapped into one, because JSON modes
+// of providers only support objects as answers.
+// The answer of the returned response contains the extracted JSON.
+func AskJSON(ctx context.Context, request CompletionRequest, result interface{}) (CompletionResponse, error) {
+	var response CompletionResponse
+
+	isWrapped := len(request.JSONSchema) > 0 && !isJSONObjectSchema(request.JSONSchema)
+	if isWrapped {
+		request.JSONSchema = toJSONObjectSchema(request.JSONSchema)
+	}
+
+	var schema *jsonschema.Schema
+	if len(request.JSONSchema) > 0 {
+		var err error
+		schema, err = CompileJSONSchema(request.JSONSchema)
+		if err != nil {
+			return response, err
+		}
+	}
+
+	request.JSONMode = true
+	request.Messages = append([]Message{}, request.Messages...)
+	request.SystemPrompt = strings.TrimSpace(request.SystemPrompt + "\n\n" + getJSONInstruction(request.JSONSchema))
+
+	maxAttempts := GetJSONMaxAttempts()
+	for attempt := 1; ; attempt++ {
+		var err error
+		response, err = Complete(ctx, request)
+		if err != nil {
+			return response, err
+		}
+
+		jsonAnswer, err := parseJSONAnswer(response.Answer, schema, isWrapped, result)
+		if err == nil {
+			response.Answer = jsonAnswer
+
+			return response, nil
+		}
+
+		if attempt >= maxAttempts {
+			return response, fmt.Errorf("no valid JSON after %v attempt(s): %w", attempt, err)
+		}
+
+		egoUtils.LogVerbose("invalid JSON answer, asking again: %v", err)
+
+		request.Messages = append(
+			request.Messages,
+			Message{
+				Content: response.Answer,
+				Role:    "assistant",
+			},
+			Message{
+				Content: fmt.Sprintf("Your answer is invalid: %v\nAnswer only with the corrected JSON.", err),
+				Role:    "user",
+			},
+		)
+	}
+}
+
+// CompileJSONSchema parses and compiles the JSON schema `schema`.
+func CompileJSONSchema(schema json.RawMessage) (*jsonschema.Schema, error) {
+	compiler := jsonschema.NewCompiler()
+
+	err := compiler.AddResource("mem://answer.schema.json", bytes.NewReader(schema))
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON schema: %w", err)
+	}
+
+	compiledSchema, err := compiler.Compile("mem://answer.schema.json")
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON schema: %w", err)
+	}
+
+	return compiledSchema, nil
+}
+
+// ExtractJSON returns the JSON object or array of `text`, which can be
+// wrapped into a Markdown code block or other text.
+func ExtractJSON(text string) string {
+	text = strings.TrimSpace(text)
+
+	match := jsonCodeBlockRegex.FindStringSubmatch(text)
+	if match != nil {
+		text = strings.TrimSpace(match[1])
+	}
+
+	if json.Valid([]byte(text)) {
+		return text
+	}
+
+	// find the outermost object or array
+	start := strings.IndexAny(text, "{[")
+	if start < 0 {
+		return text
+	}
+
+	closing := "}"
+	if text[start] == '[' {
+		closing = "]"
+	}
+
+	end := strings.LastIndex(text, closing)
+	if end < start {
+		return text
+	}
+
+	return text[start : end+1]
+}
+
+// GetJSONMaxAttempts returns the maximum number of requests of AskJSON(),
+// which is defined by the CHAT_JSON_MAX_ATTEMPTS environment variable.
+func GetJSONMaxAttempts() int {
+	str := strings.TrimSpace(os.Getenv("CHAT_JSON_MAX_ATTEMPTS"))
+	if str != "" {
+		val, err := strconv.Atoi(str)
+		if err == nil && val > 0 {
+			return val
+		}
+	}
+
+	return defaultJSONMaxAttempts // default
+}
+
+func getJSONInstruction(schema json.RawMessage) string {
+	if len(schema) == 0 {
+		return "Answer only with valid JSON, without any explanation or Markdown."
+	}
+
+	return fmt.Sprintf(
+		"Answer only with valid JSON, without any explanation or Markdown, which matches the following JSON schema:\n%v",
+		string(schema),
+	)
+}
+
+// isJSONObjectSchema checks if the root of `schema` describes a JSON object
+func isJSONObjectSchema(schema json.RawMessage) bool {
+	var root struct {
+		Type interface{} `json:"type"`
+	}
+
+	err := json.Unmarshal(schema, &root)
+	if err != nil {
+		return false
+	}
+
+	return root.Type == "object"
+}
+
+// parseJSONAnswer extracts the JSON of `answer`, validates it against
+// `schema`, if defined, unwraps it, if `isWrapped`, and parses it into
+// `result`, if defined
+func parseJSONAnswer(answer string, schema *jsonschema.Schema, isWrapped bool, result interface{}) (string, error) {
+	jsonAnswer := ExtractJSON(answer)
+
+	decoder := json.NewDecoder(strings.NewReader(jsonAnswer))
+	decoder.UseNumber()
+
+	var value interface{}
+	err := decoder.Decode(&value)
+	if err != nil {
+		return jsonAnswer, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	if schema != nil {
+		err = schema.Validate(value)
+		if err != nil {
+			return jsonAnswer, err
+		}
+	}
+
+	if isWrapped {
+		var wrappedAnswer map[string]json.RawMessage
+		err = json.Unmarshal([]byte(jsonAnswer), &wrappedAnswer)
+		if err != nil {
+			return jsonAnswer, err
+		}
+
+		jsonAnswer = string(wrappedAnswer[jsonSchemaAnswerProperty])
+	}
+
+	if result != nil {
+		err = json.Unmarshal([]byte(jsonAnswer), result)
+		if err != nil {
+			return jsonAnswer, err
+		}
+	}
+
+	return jsonAnswer, nil
+}
+
+// toJSONObjectSchema returns an object schema, which contains `schema` as
+// required property
+func toJSONObjectSchema(schema json.RawMessage) json.RawMessage {
+	wrappedSchema, err := json.Marshal(map[string]interface{}{
+		"additionalProperties": false,
+		"properties": map[string]json.RawMessage{
+			jsonSchemaAnswerProperty: schema,
+		},
+		"required": []string{jsonSchemaAnswerProperty},
+		"type":     "object",
+	})
+	if err != nil {
+		return schema
+	}
+
+	return wrappedSchema
+}
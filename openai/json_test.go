@@ -0,0 +1,165 @@
+// This file is part of the e.GPT distribution.
+// Copyright (c) Next.e.GO Mobile SE, Aachen, Germany (https://e-go-mobile.com/)
+//
+// e-gpt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, version 3.
+//
+// e-gpt is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package openai
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestExtractJSON(t *testing.T) {
+	tests := map[string]string{
+		`{"a":1}`:                                   `{"a":1}`,
+		"  [1, 2]\n":                                `[1, 2]`,
+		"```json\n{\"a\":1}\n```":                   `{"a":1}`,
+		"Here you are:\n```\n[1]\n```\nThanks":      `[1]`,
+		`The answer is {"a":{"b":2}}, as requested`: `{"a":{"b":2}}`,
+		"no json":                                   "no json",
+	}
+
+	for text, expected := range tests {
+		jsonText := ExtractJSON(text)
+		if jsonText != expected {
+			t.Errorf("ExtractJSON(%q) = %q; expected %q", text, jsonText, expected)
+		}
+	}
+}
+
+func TestIsJSONObjectSchema(t *testing.T) {
+	tests := map[string]bool{
+		`{"type":"object"}`:                      true,
+		`{"type":"object","properties":{}}`:      true,
+		`{"type":"array","items":{}}`:            false,
+		`{"type":"string"}`:                      false,
+		`{"type":["object","null"]}`:             false,
+		`{"properties":{"a":{"type":"object"}}}`: false,
+		`invalid`:                                false,
+	}
+
+	for schema, expected := range tests {
+		isObject := isJSONObjectSchema(json.RawMessage(schema))
+		if isObject != expected {
+			t.Errorf("isJSONObjectSchema(%v) = %v; expected %v", schema, isObject, expected)
+		}
+	}
+}
+
+func TestParseJSONAnswer(t *testing.T) {
+	schema, err := CompileJSONSchema(json.RawMessage(`{"type":"object","properties":{"name":{"type":"string"}},"required":["name"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var result struct {
+		Name string `json:"name"`
+	}
+	jsonAnswer, err := parseJSONAnswer("```json\n{\"name\":\"Bill\"}\n```", schema, false, &result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if jsonAnswer != `{"name":"Bill"}` || result.Name != "Bill" {
+		t.Fatalf("unexpected answer %q and result %+v", jsonAnswer, result)
+	}
+
+	_, err = parseJSONAnswer(`{"age":42}`, schema, false, nil)
+	if err == nil {
+		t.Fatal("answer does not match schema")
+	}
+
+	_, err = parseJSONAnswer(`{"name":`, nil, false, nil)
+	if err == nil {
+		t.Fatal("answer is no valid JSON")
+	}
+}
+
+func TestParseJSONAnswerOfWrappedSchema(t *testing.T) {
+	wrappedSchema := toJSONObjectSchema(json.RawMessage(`{"type":"array","items":{"type":"integer"}}`))
+	if !isJSONObjectSchema(wrappedSchema) {
+		t.Fatalf("wrapped schema is no object: %s", wrappedSchema)
+	}
+
+	schema, err := CompileJSONSchema(wrappedSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var result []int
+	jsonAnswer, err := parseJSONAnswer(`{"answer":[1,2]}`, schema, true, &result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if jsonAnswer != `[1,2]` || len(result) != 2 || result[1] != 2 {
+		t.Fatalf("unexpected answer %q and result %v", jsonAnswer, result)
+	}
+
+	_, err = parseJSONAnswer(`[1,2]`, schema, true, nil)
+	if err == nil {
+		t.Fatal("unwrapped answer does not match wrapped schema")
+	}
+}
+
+func TestSupportsJSONSchema(t *testing.T) {
+	tests := map[string]bool{
+		"":                  false, // gpt-3.5-turbo
+		"gpt-3.5-turbo":     false,
+		"gpt-4":             false,
+		"gpt-4-turbo":       false,
+		"gpt-4o":            true,
+		"gpt-4o-2024-05-13": false,
+		"gpt-4o-2024-08-06": true,
+		"gpt-4o-mini":       true,
+		"o1-mini":           false,
+		"o3-mini":           true,
+		"llama3":            false,
+	}
+
+	for model, expected := range tests {
+		isSupported := supportsJSONSchema(model)
+		if isSupported != expected {
+			t.Errorf("supportsJSONSchema(%q) = %v; expected %v", model, isSupported, expected)
+		}
+	}
+}
+
+func TestCreateChatGPTOpenAIRequestBodyResponseFormat(t *testing.T) {
+	schema := json.RawMessage(`{"type":"object"}`)
+
+	tests := []struct {
+		features       chatGPTOpenAIFeatures
+		model          string
+		responseFormat string
+	}{
+		{chatGPTOpenAIFeatures{jsonSchema: true}, "gpt-4o", "json_schema"},
+		{chatGPTOpenAIFeatures{jsonSchema: true}, "gpt-3.5-turbo", "json_object"},
+		{chatGPTOpenAIFeatures{jsonSchema: false}, "gpt-4o", "json_object"},
+	}
+
+	for _, test := range tests {
+		body, err := createChatGPTOpenAIRequestBody(CompletionRequest{
+			JSONMode:   true,
+			JSONSchema: schema,
+			Messages:   ConversationToMessages("question"),
+			Model:      test.model,
+		}, test.features)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if body.ResponseFormat == nil || body.ResponseFormat.Type != test.responseFormat {
+			t.Errorf("unexpected response format %+v for %v with %+v", body.ResponseFormat, test.model, test.features)
+		}
+	}
+}
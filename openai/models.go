@@ -0,0 +1,147 @@
+// This file is part of the e.GPT distribution.
+// Copyright (c) Next.e.GO Mobile SE, Aachen, Germany (https://e-go-mobile.com/)
+//
+// e-gpt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, version 3.
+//
+// e-gpt is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package openai
+
+import (
+	"strings"
+)
+
+// modelPrice is the price of a model in USD per 1000 tokens
+type modelPrice struct {
+	Completion float64
+	Prompt     float64
+}
+
+// The following tables describe the known models. Keys are prefixes of model
+// names, which match the name as a whole or followed by a `-`, like a version
+// suffix. So `gpt-4` matches `gpt-4-0613`, but not `gpt-4.1` or `gpt-4o`. If
+// more than one prefix matches, the longest one wins.
+//
+// Keep the tables in sync, if a model is added.
+
+// context limits of known models
+var modelContextLimits = map[string]int{
+	"claude-":                200000,
+	"gpt-3.5-turbo":          16385,
+	"gpt-3.5-turbo-0125":     16385,
+	"gpt-3.5-turbo-1106":     16385,
+	"gpt-3.5-turbo-16k":      16384,
+	"gpt-3.5-turbo-instruct": 4096,
+	"gpt-4":                  8192,
+	"gpt-4-0125-preview":     128000,
+	"gpt-4-1106-preview":     128000,
+	"gpt-4-32k":              32768,
+	"gpt-4-turbo":            128000,
+	"gpt-4-vision-preview":   128000,
+	"gpt-4.1":                1047576,
+	"gpt-4o":                 128000,
+	"gpt-5":                  400000,
+	"gpt-5-chat":             128000,
+	"o1":                     200000,
+	"o1-mini":                128000,
+	"o1-preview":             128000,
+	"o3":                     200000,
+	"o4-mini":                200000,
+}
+
+// models, which support `json_schema` as response format
+var jsonSchemaModels = map[string]bool{
+	"gpt-4.1":           true,
+	"gpt-4o":            true,
+	"gpt-4o-2024-05-13": false,
+	"gpt-5":             true,
+	"o1":                true,
+	"o1-mini":           false,
+	"o1-preview":        false,
+	"o3":                true,
+	"o4-mini":           true,
+}
+
+// prices of known models
+var modelPrices = map[string]modelPrice{
+	"claude-3-5-haiku":       {Completion: 0.004, Prompt: 0.0008},
+	"claude-3-5-sonnet":      {Completion: 0.015, Prompt: 0.003},
+	"claude-3-7-sonnet":      {Completion: 0.015, Prompt: 0.003},
+	"claude-3-haiku":         {Completion: 0.00125, Prompt: 0.00025},
+	"claude-3-opus":          {Completion: 0.075, Prompt: 0.015},
+	"claude-3-sonnet":        {Completion: 0.015, Prompt: 0.003},
+	"claude-haiku-4-5":       {Completion: 0.005, Prompt: 0.001},
+	"claude-opus-4":          {Completion: 0.075, Prompt: 0.015},
+	"claude-opus-4-5":        {Completion: 0.025, Prompt: 0.005},
+	"claude-sonnet-4":        {Completion: 0.015, Prompt: 0.003},
+	"gpt-3.5-turbo":          {Completion: 0.0015, Prompt: 0.0005},
+	"gpt-3.5-turbo-16k":      {Completion: 0.004, Prompt: 0.003},
+	"gpt-3.5-turbo-instruct": {Completion: 0.002, Prompt: 0.0015},
+	"gpt-4":                  {Completion: 0.06, Prompt: 0.03},
+	"gpt-4-0125-preview":     {Completion: 0.03, Prompt: 0.01},
+	"gpt-4-1106-preview":     {Completion: 0.03, Prompt: 0.01},
+	"gpt-4-32k":              {Completion: 0.12, Prompt: 0.06},
+	"gpt-4-turbo":            {Completion: 0.03, Prompt: 0.01},
+	"gpt-4-vision-preview":   {Completion: 0.03, Prompt: 0.01},
+	"gpt-4.1":                {Completion: 0.008, Prompt: 0.002},
+	"gpt-4.1-mini":           {Completion: 0.0016, Prompt: 0.0004},
+	"gpt-4.1-nano":           {Completion: 0.0004, Prompt: 0.0001},
+	"gpt-4o":                 {Completion: 0.01, Prompt: 0.0025},
+	"gpt-4o-2024-05-13":      {Completion: 0.015, Prompt: 0.005},
+	"gpt-4o-mini":            {Completion: 0.0006, Prompt: 0.00015},
+	"gpt-5":                  {Completion: 0.01, Prompt: 0.00125},
+	"gpt-5-mini":             {Completion: 0.002, Prompt: 0.00025},
+	"gpt-5-nano":             {Completion: 0.0004, Prompt: 0.00005},
+	"o1":                     {Completion: 0.06, Prompt: 0.015},
+	"o1-mini":                {Completion: 0.0044, Prompt: 0.0011},
+	"o1-preview":             {Completion: 0.06, Prompt: 0.015},
+	"o3":                     {Completion: 0.008, Prompt: 0.002},
+	"o3-mini":                {Completion: 0.0044, Prompt: 0.0011},
+	"o4-mini":                {Completion: 0.0044, Prompt: 0.0011},
+}
+
+// getByModelPrefix returns the value of the longest prefix in `values`,
+// which matches `model`
+func getByModelPrefix[T any](model string, values map[string]T) (T, bool) {
+	var value T
+
+	model = strings.TrimSpace(strings.ToLower(model))
+	if model == "" {
+		model = defaultModel
+	}
+
+	// Azure OpenAI names GPT-3.5 models like `gpt-35-turbo`
+	model = strings.Replace(model, "gpt-35-", "gpt-3.5-", 1)
+
+	found := false
+	matchLength := 0
+	for prefix, prefixValue := range values {
+		if isModelPrefix(model, prefix) && len(prefix) > matchLength {
+			value = prefixValue
+			found = true
+			matchLength = len(prefix)
+		}
+	}
+
+	return value, found
+}
+
+// isModelPrefix checks if `prefix` is `model` or a prefix of it, which ends
+// at a `-`, so that `gpt-4` is no prefix of `gpt-4o`
+func isModelPrefix(model string, prefix string) bool {
+	if !strings.HasPrefix(model, prefix) {
+		return false
+	}
+
+	rest := model[len(prefix):]
+
+	return rest == "" || strings.HasSuffix(prefix, "-") || strings.HasPrefix(rest, "-")
+}
@@ -0,0 +1,347 @@
+// This file is part of the e.GPT distribution.
+// Copyright (c) Next.e.GO Mobile SE, Aachen, Germany (https://e-go-mobile.com/)
+//
+// e-gpt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, version 3.
+//
+// e-gpt is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	egoUtils "github.com/egomobile/e-gpt/utils"
+)
+
+// Message represents a provider independent message of a conversation.
+type Message struct {
+	Content    string     `json:"content"`              // Message content
+	Images     []Image    `json:"images,omitempty"`     // Images of a message of role `user`, requires an ImageProvider
+	Role       string     `json:"role"`                 // Role of the message sender, like `user`, `assistant` or `tool`
+	ToolCallId string     `json:"toolCallId,omitempty"` // ID of the tool call, a message of role `tool` answers
+	ToolCalls  []ToolCall `json:"toolCalls,omitempty"`  // Tool calls of a message of role `assistant`
+}
+
+// CompletionRequest represents a provider independent chat completion request.
+type CompletionRequest struct {
+	FrequencyPenalty float64         // Frequency penalty, between -2 and 2
+	JSONMode         bool            // Request a JSON object as answer, if supported by the provider
+	JSONSchema       json.RawMessage // JSON schema, the answer has to match, if supported by the provider; implies JSONMode
+	MaxTokens        int64           // Maximum number of tokens to generate
+	Messages         []Message       // Conversation message history, without system prompt
+	Model            string          // ID of the model to use, or empty for the default one of the provider
+	NoCache          bool            // Do not use the response cache
+	PresencePenalty  float64         // Presence penalty, between -2 and 2
+	Provider         string          // Name of the provider to use, or empty for the default one
+	Stop             []string        // Sequences at which to stop generation
+	SystemPrompt     string          // System prompt message
+	Temperature      float64         // Sampling temperature to use
+	ToolChoice       string          // `auto`, `none`, `required` or the name of a tool the model must call; empty for the default of the provider
+	Tools            []Tool          // Tools the model may call, requires a ToolProvider
+	TopP             float64         // Top-p sampling cutoff, or 0 for the default one of the provider
+}
+
+// CompletionResponse represents a provider independent chat completion response.
+type CompletionResponse struct {
+	Answer    string           // Generated response message
+	Cached    bool             // Answer has been read from the response cache
+	Model     string           // The model, which generated the answer, if known
+	Provider  string           // Name of the provider, which generated the answer
+	ToolCalls []ToolCall       // Tools the model wants to call, before it can answer
+	Usage     *CompletionUsage // Token usage, if known
+}
+
+// CompletionUsage contains the number of tokens of a CompletionRequest.
+type CompletionUsage struct {
+	CompletionTokens int  // Number of tokens of the answer
+	Estimated        bool // Counted locally, because the provider did not send it
+	PromptTokens     int  // Number of tokens of the system prompt and conversation
+	TotalTokens      int  // Total number of tokens
+}
+
+// Provider describes a backend, which is able to answer chat completion requests.
+type Provider interface {
+	// Complete sends `request` to the backend and returns the generated answer.
+	Complete(ctx context.Context, request CompletionRequest) (CompletionResponse, error)
+	// Name returns the name of the provider, like `openai`.
+	Name() string
+}
+
+// ModelProvider is a Provider, which knows the model it uses by default.
+type ModelProvider interface {
+	Provider
+
+	// DefaultModel returns the model, which is used for `request`, if it does
+	// not define one, or an empty string, if it is unknown.
+	DefaultModel(request CompletionRequest) string
+}
+
+// ProviderFactory creates a new Provider instance from the current environment.
+type ProviderFactory func() (Provider, error)
+
+// ProviderRegistration contains everything the registry needs to know about a provider.
+type ProviderRegistration struct {
+	Factory      ProviderFactory // Creates a new instance of the provider
+	IsConfigured func() bool     // Checks if the environment contains settings for the provider
+	Priority     int             // Lower values are checked first, when detecting the default provider
+}
+
+var providerRegistry = make(map[string]ProviderRegistration)
+var providerRegistryLock sync.RWMutex
+
+// ConversationToMessages converts an alternating list of user and assistant
+// messages, starting with the user, to a list of Message items.
+func ConversationToMessages(conversation ...string) []Message {
+	messages := make([]Message, 0, len(conversation))
+
+	for i, content := range conversation {
+		var role string
+		if i%2 == 1 {
+			role = "assistant"
+		} else {
+			role = "user"
+		}
+
+		messages = append(messages, Message{
+			Content: content,
+			Role:    role,
+		})
+	}
+
+	return messages
+}
+
+// GetProvider creates a new instance of the provider with the given name.
+// If `name` is empty, the first name from egoUtils.GetChatProviderNames() is used
+// and if this is also empty, the configured provider with the lowest priority
+// is returned.
+func GetProvider(name string) (Provider, error) {
+	name = strings.TrimSpace(strings.ToLower(name))
+	if name == "" {
+		providerNames := egoUtils.GetChatProviderNames()
+		if len(providerNames) > 0 {
+			name = providerNames[0]
+		}
+	}
+
+	providerRegistryLock.RLock()
+	defer providerRegistryLock.RUnlock()
+
+	if name == "" {
+		// detect by environment
+		for _, providerName := range getProviderNamesUnsafe() {
+			registration := providerRegistry[providerName]
+			if registration.IsConfigured != nil && registration.IsConfigured() {
+				name = providerName
+				break
+			}
+		}
+
+		if name == "" {
+			return nil, errors.New("could not specify an API gateway")
+		}
+	}
+
+	registration, ok := providerRegistry[name]
+	if !ok {
+		return nil, fmt.Errorf("provider %v not found", name)
+	}
+
+	return registration.Factory()
+}
+
+// GetModel returns the model, which is used for `request` by the first
+// provider, or an empty string, if it is unknown.
+func GetModel(request CompletionRequest) (string, error) {
+	applyDefaultRequestOptions(&request)
+
+	providers, err := getProviderChain(request.Provider)
+	if err != nil {
+		return "", err
+	}
+
+	applyRequestModel(&request, providers)
+
+	return getRequestModel(providers[0], request), nil
+}
+
+// GetProviderNames returns the names of all registered providers, sorted by their priority.
+func GetProviderNames() []string {
+	providerRegistryLock.RLock()
+	defer providerRegistryLock.RUnlock()
+
+	return getProviderNamesUnsafe()
+}
+
+func getProviderNamesUnsafe() []string {
+	names := make([]string, 0, len(providerRegistry))
+	for name := range providerRegistry {
+		names = append(names, name)
+	}
+
+	sort.SliceStable(names, func(x, y int) bool {
+		priorityX := providerRegistry[names[x]].Priority
+		priorityY := providerRegistry[names[y]].Priority
+		if priorityX != priorityY {
+			return priorityX < priorityY
+		}
+
+		return names[x] < names[y]
+	})
+
+	return names
+}
+
+// RegisterProvider registers a provider with a specific name, which can be
+// selected by the `CHAT_API_PROVIDER` environment variable or `--provider` flag.
+// An existing registration with the same name will be replaced.
+func RegisterProvider(name string, registration ProviderRegistration) error {
+	name = strings.TrimSpace(strings.ToLower(name))
+	if name == "" {
+		return errors.New("name of provider must not be empty")
+	}
+
+	if registration.Factory == nil {
+		return errors.New("factory of provider must not be nil")
+	}
+
+	providerRegistryLock.Lock()
+	defer providerRegistryLock.Unlock()
+
+	providerRegistry[name] = registration
+
+	return nil
+}
+
+// Complete trims the conversation of `request`, fills empty options with the
+// values of `CHAT_API_*` environment variables and sends it to the provider,
+// which is defined by `request.Provider`. If this is a comma separated list,
+// the next provider is used, if the previous one is not available. The request
+// is canceled, if `ctx` is done or the timeout of egoUtils.GetApiTimeout() is reached.
+func Complete(ctx context.Context, request CompletionRequest) (CompletionResponse, error) {
+	request, providers, err := prepareCompletion(request)
+	if err != nil {
+		return CompletionResponse{}, err
+	}
+
+	return completeWithFallback(ctx, request, providers, func(ctx context.Context, provider Provider, request CompletionRequest) (CompletionResponse, error) {
+		return provider.Complete(ctx, request)
+	}, nil)
+}
+
+func prepareCompletion(request CompletionRequest) (CompletionRequest, []Provider, error) {
+	if len(request.Messages) < 1 {
+		return request, nil, errors.New("conversation must have at least one element")
+	}
+
+	request.Messages = trimMessages(request.Messages, getMaxConversationSize())
+	applyDefaultRequestOptions(&request)
+
+	providers, err := getProviderChain(request.Provider)
+	if err != nil {
+		return request, nil, err
+	}
+
+	if len(request.Tools) > 0 {
+		providers, err = getToolProviders(providers)
+		if err != nil {
+			return request, nil, err
+		}
+	}
+
+	if hasImages(request.Messages) {
+		providers, err = getImageProviders(providers)
+		if err != nil {
+			return request, nil, err
+		}
+	}
+
+	applyRequestModel(&request, providers)
+
+	return request, providers, nil
+}
+
+// applyRequestModel sets the model of `request` to the one of `CHAT_API_MODEL`,
+// if it is empty. If more than one provider can be used, the model is removed,
+// because each provider resolves its own by ModelProvider.DefaultModel().
+func applyRequestModel(request *CompletionRequest, providers []Provider) {
+	request.Model = strings.TrimSpace(request.Model)
+
+	if len(providers) > 1 {
+		if request.Model != "" {
+			egoUtils.LogVerbose("ignoring model %v, because the default model of each provider is used for a chain", request.Model)
+		}
+
+		request.Model = ""
+		return
+	}
+
+	if request.Model == "" {
+		request.Model = strings.TrimSpace(os.Getenv("CHAT_API_MODEL"))
+	}
+}
+
+// getRequestModel returns the model, which is used by `provider` for
+// `request`, or an empty string, if it is unknown
+func getRequestModel(provider Provider, request CompletionRequest) string {
+	if request.Model != "" {
+		return request.Model
+	}
+
+	modelProvider, ok := provider.(ModelProvider)
+	if ok {
+		return modelProvider.DefaultModel(request)
+	}
+
+	return ""
+}
+
+// getNextTurn returns the index of the second turn of `messages`, which
+// starts with a user message, or `len(messages)`, if there is only one turn
+func getNextTurn(messages []Message) int {
+	nextTurn := 1
+	for nextTurn < len(messages) && messages[nextTurn].Role != "user" {
+		nextTurn++
+	}
+
+	return nextTurn
+}
+
+// trimMessages removes the oldest turns of `messages`, until there are not
+// more than `maxSize` messages. The last turn is kept as a whole, so that
+// tool calls and their results are not separated from the user message.
+func trimMessages(messages []Message, maxSize int) []Message {
+	finalMessages := make([]Message, 0, len(messages))
+	finalMessages = append(finalMessages, messages...)
+
+	// conversations have to start with a user message
+	for len(finalMessages) > 1 && finalMessages[0].Role != "user" {
+		finalMessages = finalMessages[1:]
+	}
+
+	for len(finalMessages) > maxSize {
+		nextTurn := getNextTurn(finalMessages)
+		if nextTurn >= len(finalMessages) {
+			break // last turn
+		}
+
+		finalMessages = finalMessages[nextTurn:]
+	}
+
+	return finalMessages
+}
@@ -0,0 +1,404 @@
+// This file is part of the e.GPT distribution.
+// Copyright (c) Next.e.GO Mobile SE, Aachen, Germany (https://e-go-mobile.com/)
+//
+// e-gpt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, version 3.
+//
+// e-gpt is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	egoUtils "github.com/egomobile/e-gpt/utils"
+)
+
+const defaultAnthropicBaseUrl = "https://api.anthropic.com/v1"
+const defaultAnthropicModel = "claude-3-5-sonnet-latest"
+const defaultAnthropicVersion = "2023-06-01"
+
+// AnthropicProvider is a Provider, which uses the Messages API of Anthropic.
+type AnthropicProvider struct {
+	ApiKey  string // The API key for Anthropic
+	BaseUrl string // The base URL of the API, like `https://api.anthropic.com/v1`
+	Model   string // The default model, like `claude-3-5-sonnet-latest`
+	Version string // The value of the `anthropic-version` header
+}
+
+// AnthropicContentBlock is an item of the content of an Anthropic message.
+type AnthropicContentBlock struct {
+	Source *AnthropicImageSource `json:"source,omitempty"` // The image, if `Type` is `image`
+	Text   string                `json:"text,omitempty"`   // The text, if `Type` is `text`
+	Type   string                `json:"type"`             // The type, like `text` or `image`
+}
+
+// AnthropicError is the error of an Anthropic API response or stream.
+type AnthropicError struct {
+	Message string `json:"message"` // The error message
+	Type    string `json:"type"`    // The error type, like `overloaded_error`
+}
+
+// AnthropicImageSource is the source of an image of an AnthropicContentBlock.
+type AnthropicImageSource struct {
+	Data      string `json:"data"`       // The Base64 encoded data
+	MediaType string `json:"media_type"` // The MIME type, like `image/png`
+	Type      string `json:"type"`       // The type, like `base64`
+}
+
+// AnthropicMessage is a message of an Anthropic Messages API request.
+type AnthropicMessage struct {
+	Content interface{} `json:"content"` // The content as string or list of AnthropicContentBlock items
+	Role    string      `json:"role"`    // The role, `user` or `assistant`
+}
+
+// AnthropicMessagesRequestBody is the request body for the Anthropic Messages API.
+type AnthropicMessagesRequestBody struct {
+	MaxTokens     int64              `json:"max_tokens"`               // The maximum number of tokens to generate
+	Messages      []AnthropicMessage `json:"messages"`                 // The conversation without system prompt
+	Model         string             `json:"model"`                    // The model
+	StopSequences []string           `json:"stop_sequences,omitempty"` // Sequences at which to stop generation
+	Stream        bool               `json:"stream,omitempty"`         // Stream the answer as server-sent events
+	System        string             `json:"system,omitempty"`         // The system prompt
+	Temperature   float64            `json:"temperature"`              // The temperature, between 0 and 1
+	TopP          *float64           `json:"top_p,omitempty"`          // The top-p sampling cutoff
+}
+
+// AnthropicMessagesResponseBody is the response body of the Anthropic Messages API.
+type AnthropicMessagesResponseBody struct {
+	Content []AnthropicContentBlock `json:"content"` // The content blocks of the answer
+	Model   string                  `json:"model"`   // The model, which generated the answer
+	Usage   *AnthropicUsage         `json:"usage"`   // The token usage
+}
+
+// AnthropicStreamEvent is the data of a server-sent event of the Anthropic Messages API.
+type AnthropicStreamEvent struct {
+	Delta   *AnthropicStreamDelta          `json:"delta,omitempty"`   // The delta of `content_block_delta` and `message_delta` events
+	Error   *AnthropicError                `json:"error,omitempty"`   // The error of `error` events
+	Message *AnthropicMessagesResponseBody `json:"message,omitempty"` // The message of `message_start` events
+	Type    string                         `json:"type"`              // The event type, like `content_block_delta`
+	Usage   *AnthropicUsage                `json:"usage,omitempty"`   // The usage of `message_delta` events
+}
+
+// AnthropicStreamDelta is the delta of an AnthropicStreamEvent.
+type AnthropicStreamDelta struct {
+	Text string `json:"text,omitempty"` // The text, if `Type` is `text_delta`
+	Type string `json:"type,omitempty"` // The type, like `text_delta`
+}
+
+// AnthropicUsage is the token usage of an Anthropic Messages API call.
+type AnthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`  // The number of prompt tokens
+	OutputTokens int `json:"output_tokens"` // The number of completion tokens
+}
+
+func init() {
+	RegisterProvider("anthropic", ProviderRegistration{
+		Factory: func() (Provider, error) {
+			return NewAnthropicProvider()
+		},
+		IsConfigured: egoUtils.IsAnthropicConfigured,
+		Priority:     500,
+	})
+}
+
+// NewAnthropicProvider creates a new AnthropicProvider instance from the
+// `ANTHROPIC_*` environment variables.
+func NewAnthropicProvider() (*AnthropicProvider, error) {
+	apiKey := strings.TrimSpace(os.Getenv("ANTHROPIC_API_KEY"))
+	if apiKey == "" {
+		return nil, errors.New("no Anthropic API key defined")
+	}
+
+	baseUrl := strings.TrimSpace(os.Getenv("ANTHROPIC_BASE_URL"))
+	if baseUrl == "" {
+		baseUrl = defaultAnthropicBaseUrl
+	}
+
+	model := strings.TrimSpace(os.Getenv("ANTHROPIC_MODEL"))
+	if model == "" {
+		model = defaultAnthropicModel
+	}
+
+	version := strings.TrimSpace(os.Getenv("ANTHROPIC_VERSION"))
+	if version == "" {
+		version = defaultAnthropicVersion
+	}
+
+	return &AnthropicProvider{
+		ApiKey:  apiKey,
+		BaseUrl: baseUrl,
+		Model:   model,
+		Version: version,
+	}, nil
+}
+
+// Complete implements Provider.Complete().
+func (p *AnthropicProvider) Complete(ctx context.Context, request CompletionRequest) (CompletionResponse, error) {
+	var response CompletionResponse
+
+	payload, err := p.createRequestBody(request)
+	if err != nil {
+		return response, err
+	}
+
+	messagesResponse, err := p.sendRequest(ctx, payload)
+	if err != nil {
+		return response, err
+	}
+
+	defer messagesResponse.Body.Close()
+
+	bodyData, err := io.ReadAll(messagesResponse.Body)
+	if err != nil {
+		return response, err
+	}
+
+	var messagesResponseBody AnthropicMessagesResponseBody
+	err = json.Unmarshal(bodyData, &messagesResponseBody)
+	if err != nil {
+		return response, err
+	}
+
+	var answer strings.Builder
+	for _, block := range messagesResponseBody.Content {
+		if block.Type == "text" {
+			answer.WriteString(block.Text)
+		}
+	}
+
+	response.Answer = answer.String()
+	response.Model = messagesResponseBody.Model
+	if response.Model == "" {
+		response.Model = payload.Model
+	}
+	response.Provider = p.Name()
+	response.Usage = toAnthropicCompletionUsage(messagesResponseBody.Usage)
+
+	return response, nil
+}
+
+// CompleteStream implements StreamingProvider.CompleteStream().
+func (p *AnthropicProvider) CompleteStream(ctx context.Context, request CompletionRequest, onToken TokenHandler) (CompletionResponse, error) {
+	var response CompletionResponse
+
+	payload, err := p.createRequestBody(request)
+	if err != nil {
+		return response, err
+	}
+
+	payload.Stream = true
+
+	messagesResponse, err := p.sendRequest(ctx, payload)
+	if err != nil {
+		return response, err
+	}
+
+	defer messagesResponse.Body.Close()
+
+	var answer strings.Builder
+	var usage AnthropicUsage
+
+	err = readServerSentEvents(messagesResponse.Body, func(data string) (bool, error) {
+		var event AnthropicStreamEvent
+		err := json.Unmarshal([]byte(data), &event)
+		if err != nil {
+			return false, err
+		}
+
+		switch event.Type {
+		case "content_block_delta":
+			if event.Delta == nil || event.Delta.Type != "text_delta" || event.Delta.Text == "" {
+				break
+			}
+
+			answer.WriteString(event.Delta.Text)
+
+			return true, onToken(event.Delta.Text)
+
+		case "error":
+			if event.Error != nil {
+				return false, fmt.Errorf("%v: %v", event.Error.Type, event.Error.Message)
+			}
+
+			return false, errors.New("unknown error")
+
+		case "message_delta":
+			if event.Usage != nil {
+				usage.OutputTokens = event.Usage.OutputTokens
+			}
+
+		case "message_start":
+			if event.Message != nil {
+				response.Model = event.Message.Model
+				if event.Message.Usage != nil {
+					usage.InputTokens = event.Message.Usage.InputTokens
+				}
+			}
+
+		case "message_stop":
+			return false, nil
+		}
+
+		return true, nil
+	})
+	if err != nil {
+		return response, err
+	}
+
+	response.Answer = answer.String()
+	if response.Model == "" {
+		response.Model = payload.Model
+	}
+	response.Provider = p.Name()
+	if usage.InputTokens > 0 || usage.OutputTokens > 0 {
+		response.Usage = toAnthropicCompletionUsage(&usage)
+	}
+
+	return response, nil
+}
+
+// DefaultModel implements ModelProvider.DefaultModel().
+func (p *AnthropicProvider) DefaultModel(request CompletionRequest) string {
+	if p.Model == "" {
+		return defaultAnthropicModel
+	}
+
+	return p.Model
+}
+
+// Name implements Provider.Name().
+func (p *AnthropicProvider) Name() string {
+	return "anthropic"
+}
+
+// SupportsImages implements ImageProvider.SupportsImages().
+func (p *AnthropicProvider) SupportsImages() bool {
+	return true
+}
+
+func (p *AnthropicProvider) createRequestBody(request CompletionRequest) (AnthropicMessagesRequestBody, error) {
+	if len(request.Messages)%2 == 0 {
+		return AnthropicMessagesRequestBody{}, errors.New("number of conversation elements must be odd")
+	}
+
+	messages := make([]AnthropicMessage, 0, len(request.Messages))
+	for _, message := range request.Messages {
+		var content interface{} = message.Content
+		if len(message.Images) > 0 {
+			var blocks []AnthropicContentBlock
+			for _, image := range message.Images {
+				blocks = append(blocks, AnthropicContentBlock{
+					Source: &AnthropicImageSource{
+						Data:      image.Base64(),
+						MediaType: image.MimeType,
+						Type:      "base64",
+					},
+					Type: "image",
+				})
+			}
+			blocks = append(blocks, AnthropicContentBlock{
+				Text: message.Content,
+				Type: "text",
+			})
+
+			content = blocks
+		}
+
+		messages = append(messages, AnthropicMessage{
+			Content: content,
+			Role:    message.Role,
+		})
+	}
+
+	maxTokens := request.MaxTokens
+	if maxTokens < 1 {
+		maxTokens = defaultMaxTokens
+	}
+
+	model := request.Model
+	if model == "" {
+		model = p.DefaultModel(request)
+	}
+
+	// Anthropic only supports temperatures between 0 and 1
+	temperature := request.Temperature
+	if temperature > 1 {
+		temperature = 1
+	}
+
+	var topP *float64 = nil
+	if request.TopP != 0 {
+		topP = &request.TopP
+	}
+
+	return AnthropicMessagesRequestBody{
+		MaxTokens:     maxTokens,
+		Messages:      messages,
+		Model:         model,
+		StopSequences: request.Stop,
+		System:        request.SystemPrompt,
+		Temperature:   temperature,
+		TopP:          topP,
+	}, nil
+}
+
+func (p *AnthropicProvider) sendRequest(ctx context.Context, payload AnthropicMessagesRequestBody) (*http.Response, error) {
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	url := strings.TrimRight(p.BaseUrl, "/") + "/messages"
+
+	messagesResponse, err := egoUtils.SendHttpRequest(ctx, func(ctx context.Context) (*http.Request, error) {
+		messagesRequest, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(payloadJSON))
+		if err != nil {
+			return nil, err
+		}
+
+		messagesRequest.Header.Set("anthropic-version", p.Version)
+		messagesRequest.Header.Set("Content-Type", "application/json; CHARSET=UTF-8")
+		messagesRequest.Header.Set("x-api-key", p.ApiKey)
+
+		return messagesRequest, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if messagesResponse.StatusCode != 200 {
+		defer messagesResponse.Body.Close()
+
+		return nil, egoUtils.NewResponseError("unexpected response: ", messagesResponse)
+	}
+
+	return messagesResponse, nil
+}
+
+func toAnthropicCompletionUsage(usage *AnthropicUsage) *CompletionUsage {
+	if usage == nil {
+		return nil
+	}
+
+	return &CompletionUsage{
+		CompletionTokens: usage.OutputTokens,
+		PromptTokens:     usage.InputTokens,
+		TotalTokens:      usage.InputTokens + usage.OutputTokens,
+	}
+}
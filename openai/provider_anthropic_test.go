@@ -0,0 +1,220 @@
+// This file is part of the e.GPT distribution.
+// Copyright (c) Next.e.GO Mobile SE, Aachen, Germany (https://e-go-mobile.com/)
+//
+// e-gpt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, version 3.
+//
+// e-gpt is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newTestAnthropicProvider(baseUrl string) *AnthropicProvider {
+	return &AnthropicProvider{
+		ApiKey:  "test",
+		BaseUrl: baseUrl,
+		Model:   "claude-test",
+		Version: defaultAnthropicVersion,
+	}
+}
+
+func TestAnthropicCreateRequestBody(t *testing.T) {
+	provider := newTestAnthropicProvider("")
+
+	tests := []struct {
+		name        string
+		request     CompletionRequest
+		model       string
+		temperature float64
+	}{
+		{"default model", CompletionRequest{Temperature: 0.5}, "claude-test", 0.5},
+		{"request model", CompletionRequest{Model: "claude-other", Temperature: 0}, "claude-other", 0},
+		{"maximum temperature", CompletionRequest{Temperature: 1}, "claude-test", 1},
+		{"clamped temperature", CompletionRequest{Temperature: 1.5}, "claude-test", 1},
+		{"clamped OpenAI maximum", CompletionRequest{Temperature: 2}, "claude-test", 1},
+	}
+
+	for _, test := range tests {
+		test.request.Messages = ConversationToMessages("question")
+
+		body, err := provider.createRequestBody(test.request)
+		if err != nil {
+			t.Fatalf("%v: %v", test.name, err)
+		}
+		if body.Model != test.model {
+			t.Errorf("%v: model is %v; expected %v", test.name, body.Model, test.model)
+		}
+		if body.Temperature != test.temperature {
+			t.Errorf("%v: temperature is %v; expected %v", test.name, body.Temperature, test.temperature)
+		}
+		if body.MaxTokens != defaultMaxTokens {
+			t.Errorf("%v: max tokens are %v; expected %v", test.name, body.MaxTokens, defaultMaxTokens)
+		}
+		if body.TopP != nil {
+			t.Errorf("%v: top-p must not be sent", test.name)
+		}
+	}
+
+	_, err := provider.createRequestBody(CompletionRequest{
+		Messages: ConversationToMessages("question", "answer"),
+	})
+	if err == nil {
+		t.Error("expected error for even number of messages")
+	}
+}
+
+func TestAnthropicCreateRequestBodyWithImages(t *testing.T) {
+	provider := newTestAnthropicProvider("")
+
+	messages := ConversationToMessages("first question", "first answer", "what is on the images?")
+	messages[2].Images = []Image{
+		{Data: []byte("png"), MimeType: "image/png"},
+		{Data: []byte("jpeg"), MimeType: "image/jpeg"},
+	}
+
+	body, err := provider.createRequestBody(CompletionRequest{
+		Messages:     messages,
+		SystemPrompt: "system",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if body.System != "system" {
+		t.Errorf("unexpected system prompt %q", body.System)
+	}
+	if body.Messages[0].Content != "first question" || body.Messages[1].Content != "first answer" {
+		t.Errorf("messages without images must be strings: %+v", body.Messages[:2])
+	}
+
+	blocks, ok := body.Messages[2].Content.([]AnthropicContentBlock)
+	if !ok || len(blocks) != 3 {
+		t.Fatalf("expected 3 content blocks, got %#v", body.Messages[2].Content)
+	}
+
+	// images first, s. https://docs.anthropic.com/en/docs/build-with-claude/vision
+	expected := []struct {
+		blockType string
+		mediaType string
+		data      string
+	}{
+		{"image", "image/png", Image{Data: []byte("png")}.Base64()},
+		{"image", "image/jpeg", Image{Data: []byte("jpeg")}.Base64()},
+		{"text", "", ""},
+	}
+	for i, block := range blocks {
+		if block.Type != expected[i].blockType {
+			t.Fatalf("block %v has type %v; expected %v", i, block.Type, expected[i].blockType)
+		}
+
+		if block.Type == "text" {
+			if block.Text != "what is on the images?" {
+				t.Errorf("unexpected text %q", block.Text)
+			}
+		} else if block.Source == nil || block.Source.MediaType != expected[i].mediaType || block.Source.Data != expected[i].data {
+			t.Errorf("block %v has source %+v; expected %v", i, block.Source, expected[i].mediaType)
+		}
+	}
+}
+
+func TestAnthropicCompleteStream(t *testing.T) {
+	events := []string{
+		`{"type":"message_start","message":{"model":"claude-3-5-sonnet-20241022","content":[],"usage":{"input_tokens":25,"output_tokens":1}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+		`{"type":"ping"}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hello"}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":" world"}}`,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":15}}`,
+		`{"type":"message_stop"}`,
+	}
+
+	var payload AnthropicMessagesRequestBody
+	var apiKey string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apiKey = r.Header.Get("x-api-key")
+		json.NewDecoder(r.Body).Decode(&payload)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range events {
+			var eventType struct {
+				Type string `json:"type"`
+			}
+			json.Unmarshal([]byte(event), &eventType)
+
+			fmt.Fprintf(w, "event: %v\ndata: %v\n\n", eventType.Type, event)
+		}
+	}))
+	defer server.Close()
+
+	provider := newTestAnthropicProvider(server.URL)
+
+	var tokens []string
+	response, err := provider.CompleteStream(context.Background(), CompletionRequest{
+		Messages: ConversationToMessages("question"),
+	}, func(token string) error {
+		tokens = append(tokens, token)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if apiKey != "test" || !payload.Stream {
+		t.Errorf("unexpected request with key %q and stream %v", apiKey, payload.Stream)
+	}
+	if response.Answer != "Hello world" || strings.Join(tokens, "|") != "Hello| world" {
+		t.Errorf("unexpected answer %q with tokens %q", response.Answer, tokens)
+	}
+	if response.Model != "claude-3-5-sonnet-20241022" || response.Provider != "anthropic" {
+		t.Errorf("unexpected model %v of provider %v", response.Model, response.Provider)
+	}
+
+	expectedUsage := CompletionUsage{CompletionTokens: 15, PromptTokens: 25, TotalTokens: 40}
+	if response.Usage == nil || *response.Usage != expectedUsage {
+		t.Errorf("usage is %+v; expected %+v", response.Usage, expectedUsage)
+	}
+}
+
+func TestAnthropicCompleteStreamWithError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"model\":\"claude-test\",\"usage\":{\"input_tokens\":10}}}\n\n")
+		fmt.Fprint(w, "event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"delta\":{\"type\":\"text_delta\",\"text\":\"Hel\"}}\n\n")
+		fmt.Fprint(w, "event: error\ndata: {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\",\"message\":\"Overloaded\"}}\n\n")
+		fmt.Fprint(w, "event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"delta\":{\"type\":\"text_delta\",\"text\":\"lo\"}}\n\n")
+	}))
+	defer server.Close()
+
+	provider := newTestAnthropicProvider(server.URL)
+
+	var tokens []string
+	_, err := provider.CompleteStream(context.Background(), CompletionRequest{
+		Messages: ConversationToMessages("question"),
+	}, func(token string) error {
+		tokens = append(tokens, token)
+		return nil
+	})
+	if err == nil || err.Error() != "overloaded_error: Overloaded" {
+		t.Fatalf("expected overloaded error, got %v", err)
+	}
+	if strings.Join(tokens, "") != "Hel" {
+		t.Errorf("events after the error must be ignored, got tokens %q", tokens)
+	}
+}
@@ -0,0 +1,133 @@
+// This file is part of the e.GPT distribution.
+// Copyright (c) Next.e.GO Mobile SE, Aachen, Germany (https://e-go-mobile.com/)
+//
+// e-gpt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, version 3.
+//
+// e-gpt is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package openai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	egoUtils "github.com/egomobile/e-gpt/utils"
+)
+
+const defaultAzureOpenAIApiVersion = "2024-10-21"
+
+// first API version of Azure OpenAI, which supports `json_schema`
+const azureOpenAIJSONSchemaApiVersion = "2024-08-01-preview"
+
+// first API version of Azure OpenAI, which supports `stream_options`
+const azureOpenAIStreamUsageApiVersion = "2024-09-01-preview"
+
+// AzureOpenAIProvider is a Provider, which uses a deployment of the
+// Azure OpenAI Service.
+type AzureOpenAIProvider struct {
+	ApiKey     string // The API key of the Azure OpenAI resource
+	ApiVersion string // The value of the `api-version` query parameter
+	Deployment string // The name of the deployment
+	Endpoint   string // The endpoint of the resource, like `https://example.openai.azure.com`
+}
+
+func init() {
+	RegisterProvider("azure", ProviderRegistration{
+		Factory: func() (Provider, error) {
+			return NewAzureOpenAIProvider()
+		},
+		IsConfigured: egoUtils.IsAzureOpenAIConfigured,
+		Priority:     300,
+	})
+}
+
+// NewAzureOpenAIProvider creates a new AzureOpenAIProvider instance from the
+// `AZURE_OPENAI_*` environment variables.
+func NewAzureOpenAIProvider() (*AzureOpenAIProvider, error) {
+	endpoint := strings.TrimSpace(os.Getenv("AZURE_OPENAI_ENDPOINT"))
+	if endpoint == "" {
+		return nil, errors.New("no Azure OpenAI endpoint defined")
+	}
+
+	deployment := strings.TrimSpace(os.Getenv("AZURE_OPENAI_DEPLOYMENT"))
+	if deployment == "" {
+		return nil, errors.New("no Azure OpenAI deployment defined")
+	}
+
+	apiKey := strings.TrimSpace(os.Getenv("AZURE_OPENAI_API_KEY"))
+	if apiKey == "" {
+		return nil, errors.New("no Azure OpenAI API key defined")
+	}
+
+	apiVersion := strings.TrimSpace(os.Getenv("AZURE_OPENAI_API_VERSION"))
+	if apiVersion == "" {
+		apiVersion = defaultAzureOpenAIApiVersion
+	}
+
+	return &AzureOpenAIProvider{
+		ApiKey:     apiKey,
+		ApiVersion: apiVersion,
+		Deployment: deployment,
+		Endpoint:   endpoint,
+	}, nil
+}
+
+// Complete implements Provider.Complete().
+func (p *AzureOpenAIProvider) Complete(ctx context.Context, request CompletionRequest) (CompletionResponse, error) {
+	return completeChatGPTOpenAI(ctx, p.Name(), request, p.sendRequest, p.getFeatures())
+}
+
+// CompleteStream implements StreamingProvider.CompleteStream().
+func (p *AzureOpenAIProvider) CompleteStream(ctx context.Context, request CompletionRequest, onToken TokenHandler) (CompletionResponse, error) {
+	return completeChatGPTOpenAIStream(ctx, p.Name(), request, p.sendRequest, p.getFeatures(), onToken)
+}
+
+// Name implements Provider.Name().
+func (p *AzureOpenAIProvider) Name() string {
+	return "azure"
+}
+
+// SupportsImages implements ImageProvider.SupportsImages().
+func (p *AzureOpenAIProvider) SupportsImages() bool {
+	return true
+}
+
+// SupportsTools implements ToolProvider.SupportsTools().
+func (p *AzureOpenAIProvider) SupportsTools() bool {
+	return true
+}
+
+func (p *AzureOpenAIProvider) getFeatures() chatGPTOpenAIFeatures {
+	// API versions are dates, like `2024-10-21` or `2024-09-01-preview`
+	return chatGPTOpenAIFeatures{
+		jsonSchema:  p.ApiVersion >= azureOpenAIJSONSchemaApiVersion,
+		streamUsage: p.ApiVersion >= azureOpenAIStreamUsageApiVersion,
+	}
+}
+
+func (p *AzureOpenAIProvider) sendRequest(ctx context.Context, payload ChatGPTOpenAIRequestBody) (*http.Response, error) {
+	// the model is defined by the deployment
+	requestUrl := fmt.Sprintf(
+		"%v/openai/deployments/%v/chat/completions?api-version=%v",
+		strings.TrimRight(p.Endpoint, "/"),
+		url.PathEscape(p.Deployment),
+		url.QueryEscape(p.ApiVersion),
+	)
+
+	return sendChatGPTOpenAIRequest(ctx, requestUrl, payload, func(chatRequest *http.Request) {
+		chatRequest.Header.Set("api-key", p.ApiKey)
+	})
+}
@@ -0,0 +1,459 @@
+// This file is part of the e.GPT distribution.
+// Copyright (c) Next.e.GO Mobile SE, Aachen, Germany (https://e-go-mobile.com/)
+//
+// e-gpt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, version 3.
+//
+// e-gpt is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	egoUtils "github.com/egomobile/e-gpt/utils"
+)
+
+const defaultOpenAIBaseUrl = "https://api.openai.com/v1"
+
+// OpenAIProvider is a Provider, which uses the official API of OpenAI.
+type OpenAIProvider struct {
+	ApiKey  string // The API key for OpenAI
+	BaseUrl string // The base URL of the API, like `https://api.openai.com/v1`
+}
+
+// chatGPTOpenAIFeatures describes the optional features of an API, which is
+// compatible to the chat completions API of OpenAI
+type chatGPTOpenAIFeatures struct {
+	jsonSchema  bool // `json_schema` response format is supported by known models
+	streamUsage bool // token usage is sent with `stream_options`
+}
+
+// chatGPTOpenAIRequestSender sends `payload` to an API, which is compatible
+// to the chat completions API of OpenAI
+type chatGPTOpenAIRequestSender func(ctx context.Context, payload ChatGPTOpenAIRequestBody) (*http.Response, error)
+
+func init() {
+	RegisterProvider("openai", ProviderRegistration{
+		Factory: func() (Provider, error) {
+			return NewOpenAIProvider()
+		},
+		IsConfigured: func() bool {
+			return strings.TrimSpace(os.Getenv("OPENAI_API_KEY")) != ""
+		},
+		Priority: 100,
+	})
+}
+
+// NewOpenAIProvider creates a new OpenAIProvider instance from the
+// `OPENAI_API_KEY` and `OPENAI_BASE_URL` environment variables.
+func NewOpenAIProvider() (*OpenAIProvider, error) {
+	openaiApiKey := strings.TrimSpace(os.Getenv("OPENAI_API_KEY"))
+	if openaiApiKey == "" {
+		return nil, errors.New("no OpenAI API key defined")
+	}
+
+	baseUrl := strings.TrimSpace(os.Getenv("OPENAI_BASE_URL"))
+	if baseUrl == "" {
+		baseUrl = defaultOpenAIBaseUrl
+	}
+
+	return &OpenAIProvider{
+		ApiKey:  openaiApiKey,
+		BaseUrl: baseUrl,
+	}, nil
+}
+
+// Complete implements Provider.Complete().
+func (p *OpenAIProvider) Complete(ctx context.Context, request CompletionRequest) (CompletionResponse, error) {
+	return completeChatGPTOpenAI(ctx, p.Name(), request, p.sendRequest, p.getFeatures())
+}
+
+// CompleteStream implements StreamingProvider.CompleteStream().
+func (p *OpenAIProvider) CompleteStream(ctx context.Context, request CompletionRequest, onToken TokenHandler) (CompletionResponse, error) {
+	return completeChatGPTOpenAIStream(ctx, p.Name(), request, p.sendRequest, p.getFeatures(), onToken)
+}
+
+// DefaultModel implements ModelProvider.DefaultModel().
+func (p *OpenAIProvider) DefaultModel(request CompletionRequest) string {
+	return getChatGPTOpenAIDefaultModel(request)
+}
+
+// Name implements Provider.Name().
+func (p *OpenAIProvider) Name() string {
+	return "openai"
+}
+
+// SupportsImages implements ImageProvider.SupportsImages().
+func (p *OpenAIProvider) SupportsImages() bool {
+	return true
+}
+
+// SupportsTools implements ToolProvider.SupportsTools().
+func (p *OpenAIProvider) SupportsTools() bool {
+	return true
+}
+
+func (p *OpenAIProvider) getFeatures() chatGPTOpenAIFeatures {
+	return chatGPTOpenAIFeatures{
+		jsonSchema:  true,
+		streamUsage: true,
+	}
+}
+
+func (p *OpenAIProvider) sendRequest(ctx context.Context, payload ChatGPTOpenAIRequestBody) (*http.Response, error) {
+	url := getChatCompletionsUrl(p.BaseUrl)
+
+	return sendChatGPTOpenAIRequest(ctx, url, payload, func(chatRequest *http.Request) {
+		chatRequest.Header.Set("Authorization", fmt.Sprintf("Bearer %v", p.ApiKey))
+	})
+}
+
+// completeChatGPTOpenAI sends `request` via `send` and returns the answer
+func completeChatGPTOpenAI(ctx context.Context, providerName string, request CompletionRequest, send chatGPTOpenAIRequestSender, features chatGPTOpenAIFeatures) (CompletionResponse, error) {
+	var response CompletionResponse
+
+	payload, err := createChatGPTOpenAIRequestBody(request, features)
+	if err != nil {
+		return response, err
+	}
+
+	chatResponse, err := send(ctx, payload)
+	if err != nil {
+		return response, err
+	}
+
+	defer chatResponse.Body.Close()
+
+	bodyData, err := io.ReadAll(chatResponse.Body)
+	if err != nil {
+		return response, err
+	}
+
+	var chatResponseBody ChatGPTOpenAIResponseBody
+	err = json.Unmarshal(bodyData, &chatResponseBody)
+	if err != nil {
+		return response, err
+	}
+
+	response.Model = chatResponseBody.Model
+	if response.Model == "" {
+		response.Model = payload.Model
+	}
+	response.Provider = providerName
+	response.Usage = toCompletionUsage(chatResponseBody.Usage)
+	if len(chatResponseBody.Choices) > 0 {
+		response.Answer = chatResponseBody.Choices[0].Message.GetText()
+		response.ToolCalls = toToolCalls(chatResponseBody.Choices[0].Message.ToolCalls)
+	}
+
+	return response, nil
+}
+
+// completeChatGPTOpenAIStream sends `request` via `send` and calls `onToken`
+// for each part of the answer, which is received as server-sent event; if the
+// API does not send the token usage, it is estimated later
+func completeChatGPTOpenAIStream(ctx context.Context, providerName string, request CompletionRequest, send chatGPTOpenAIRequestSender, features chatGPTOpenAIFeatures, onToken TokenHandler) (CompletionResponse, error) {
+	var response CompletionResponse
+
+	if len(request.Tools) > 0 {
+		// tool calls are not streamed
+		response, err := completeChatGPTOpenAI(ctx, providerName, request, send, features)
+		if err == nil && response.Answer != "" {
+			err = onToken(response.Answer)
+		}
+
+		return response, err
+	}
+
+	payload, err := createChatGPTOpenAIRequestBody(request, features)
+	if err != nil {
+		return response, err
+	}
+
+	payload.Stream = true
+	if features.streamUsage {
+		payload.StreamOptions = &ChatGPTOpenAIStreamOptions{
+			IncludeUsage: true,
+		}
+	}
+
+	chatResponse, err := send(ctx, payload)
+	if err != nil {
+		return response, err
+	}
+
+	defer chatResponse.Body.Close()
+
+	var answer strings.Builder
+
+	err = readServerSentEvents(chatResponse.Body, func(data string) (bool, error) {
+		if data == "[DONE]" {
+			return false, nil
+		}
+
+		var chunk ChatGPTOpenAIStreamResponseBody
+		err := json.Unmarshal([]byte(data), &chunk)
+		if err != nil {
+			return false, err
+		}
+
+		if chunk.Model != "" {
+			response.Model = chunk.Model
+		}
+		if chunk.Usage != nil {
+			response.Usage = toCompletionUsage(chunk.Usage)
+		}
+
+		if len(chunk.Choices) < 1 {
+			return true, nil
+		}
+
+		token := chunk.Choices[0].Delta.GetText()
+		if token == "" {
+			return true, nil
+		}
+
+		answer.WriteString(token)
+
+		return true, onToken(token)
+	})
+	if err != nil {
+		return response, err
+	}
+
+	response.Answer = answer.String()
+	response.Provider = providerName
+	if response.Model == "" {
+		response.Model = payload.Model
+	}
+
+	return response, nil
+}
+
+func createChatGPTOpenAIRequestBody(request CompletionRequest, features chatGPTOpenAIFeatures) (ChatGPTOpenAIRequestBody, error) {
+	if len(request.Messages)%2 == 0 && !hasToolMessages(request.Messages) {
+		return ChatGPTOpenAIRequestBody{}, errors.New("number of conversation elements must be odd")
+	}
+
+	messages := make([]ChatGPTOpenAIMessage, 0)
+
+	messages = append(messages, ChatGPTOpenAIMessage{
+		Content: request.SystemPrompt,
+		Role:    "system",
+	})
+	for _, message := range request.Messages {
+		var toolCalls []ChatGPTOpenAIToolCall
+		for _, toolCall := range message.ToolCalls {
+			toolCalls = append(toolCalls, ChatGPTOpenAIToolCall{
+				Function: ChatGPTOpenAIFunctionCall{
+					Arguments: toolCall.Arguments,
+					Name:      toolCall.Name,
+				},
+				Id:   toolCall.Id,
+				Type: "function",
+			})
+		}
+
+		var content interface{} = message.Content
+		if len(message.Images) > 0 {
+			parts := []ChatGPTOpenAIContentPart{
+				{
+					Text: message.Content,
+					Type: "text",
+				},
+			}
+			for _, image := range message.Images {
+				parts = append(parts, ChatGPTOpenAIContentPart{
+					ImageUrl: &ChatGPTOpenAIImageUrl{
+						Url: image.DataUrl(),
+					},
+					Type: "image_url",
+				})
+			}
+
+			content = parts
+		}
+
+		messages = append(messages, ChatGPTOpenAIMessage{
+			Content:    content,
+			Role:       message.Role,
+			ToolCallId: message.ToolCallId,
+			ToolCalls:  toolCalls,
+		})
+	}
+
+	model := request.Model
+	if model == "" {
+		model = getChatGPTOpenAIDefaultModel(request)
+	}
+
+	var responseFormat *ChatGPTOpenAIResponseFormat = nil
+	if len(request.JSONSchema) > 0 && features.jsonSchema && supportsJSONSchema(model) {
+		responseFormat = &ChatGPTOpenAIResponseFormat{
+			JSONSchema: &ChatGPTOpenAIJSONSchema{
+				Name:   "answer",
+				Schema: request.JSONSchema,
+			},
+			Type: "json_schema",
+		}
+	} else if request.JSONMode || len(request.JSONSchema) > 0 {
+		// the answer is validated locally
+		responseFormat = &ChatGPTOpenAIResponseFormat{
+			Type: "json_object",
+		}
+	}
+
+	var tools []ChatGPTOpenAITool
+	for _, tool := range request.Tools {
+		tools = append(tools, ChatGPTOpenAITool{
+			Function: ChatGPTOpenAIFunction{
+				Description: tool.Description,
+				Name:        tool.Name,
+				Parameters:  tool.Parameters,
+			},
+			Type: "function",
+		})
+	}
+
+	var toolChoice interface{} = nil
+	switch request.ToolChoice {
+	case "":
+		break
+	case "auto", "none", "required":
+		toolChoice = request.ToolChoice
+	default:
+		toolChoice = ChatGPTOpenAIToolChoice{
+			Function: ChatGPTOpenAIToolChoiceFunction{
+				Name: request.ToolChoice,
+			},
+			Type: "function",
+		}
+	}
+
+	maxTokens := request.MaxTokens
+	if maxTokens < 1 {
+		maxTokens = defaultMaxTokens
+	}
+
+	topP := request.TopP
+	if topP == 0 {
+		topP = 1
+	}
+
+	var stop *interface{} = nil
+	if len(request.Stop) > 0 {
+		var stopValue interface{} = request.Stop
+		stop = &stopValue
+	}
+
+	return ChatGPTOpenAIRequestBody{
+		FrequencyPenalty: request.FrequencyPenalty,
+		MaxTokens:        maxTokens,
+		Messages:         messages,
+		Model:            model,
+		PresencePenalty:  request.PresencePenalty,
+		ResponseFormat:   responseFormat,
+		Stop:             stop,
+		Temperature:      request.Temperature,
+		ToolChoice:       toolChoice,
+		Tools:            tools,
+		TopP:             topP,
+	}, nil
+}
+
+// getChatCompletionsUrl returns the URL of the chat completions endpoint
+// of the API with `baseUrl`
+func getChatCompletionsUrl(baseUrl string) string {
+	return strings.TrimRight(baseUrl, "/") + "/chat/completions"
+}
+
+// getChatGPTOpenAIDefaultModel returns the model, which is used, if `request`
+// does not define one
+func getChatGPTOpenAIDefaultModel(request CompletionRequest) string {
+	if hasImages(request.Messages) {
+		return defaultVisionModel
+	}
+
+	return defaultModel
+}
+
+// sendChatGPTOpenAIRequest sends `payload` to `url` and returns the response,
+// if it is successful. `setAuthorization` sets the headers, which authorize the request.
+func sendChatGPTOpenAIRequest(ctx context.Context, url string, payload ChatGPTOpenAIRequestBody, setAuthorization func(chatRequest *http.Request)) (*http.Response, error) {
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	chatResponse, err := egoUtils.SendHttpRequest(ctx, func(ctx context.Context) (*http.Request, error) {
+		chatRequest, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(payloadJSON))
+		if err != nil {
+			return nil, err
+		}
+
+		setAuthorization(chatRequest)
+		chatRequest.Header.Set("Content-Type", "application/json; CHARSET=UTF-8")
+
+		return chatRequest, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if chatResponse.StatusCode != 200 {
+		defer chatResponse.Body.Close()
+
+		return nil, egoUtils.NewResponseError("unexpected response: ", chatResponse)
+	}
+
+	return chatResponse, nil
+}
+
+// supportsJSONSchema checks if `model` is known to support `json_schema`
+// as response format
+func supportsJSONSchema(model string) bool {
+	isSupported, ok := getByModelPrefix(model, jsonSchemaModels)
+
+	return ok && isSupported
+}
+
+func toCompletionUsage(usage *ChatGPTOpenAIUsage) *CompletionUsage {
+	if usage == nil {
+		return nil
+	}
+
+	return &CompletionUsage{
+		CompletionTokens: usage.CompletionTokens,
+		PromptTokens:     usage.PromptTokens,
+		TotalTokens:      usage.TotalTokens,
+	}
+}
+
+func toToolCalls(chatToolCalls []ChatGPTOpenAIToolCall) []ToolCall {
+	var toolCalls []ToolCall
+	for _, chatToolCall := range chatToolCalls {
+		toolCalls = append(toolCalls, ToolCall{
+			Arguments: chatToolCall.Function.Arguments,
+			Id:        chatToolCall.Id,
+			Name:      chatToolCall.Function.Name,
+		})
+	}
+
+	return toolCalls
+}
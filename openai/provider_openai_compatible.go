@@ -0,0 +1,136 @@
+// This file is part of the e.GPT distribution.
+// Copyright (c) Next.e.GO Mobile SE, Aachen, Germany (https://e-go-mobile.com/)
+//
+// e-gpt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, version 3.
+//
+// e-gpt is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package openai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	egoUtils "github.com/egomobile/e-gpt/utils"
+)
+
+// OpenAICompatibleProvider is a Provider, which uses a server with an API
+// compatible to the one of OpenAI, like Ollama, llama.cpp or vLLM.
+type OpenAICompatibleProvider struct {
+	ApiKey      string // The optional API key
+	BaseUrl     string // The base URL of the API, like `http://localhost:11434/v1`
+	ImagesModel bool   // The model is able to handle images
+	Model       string // The default model, like `llama3.1`
+}
+
+func init() {
+	RegisterProvider("openai-compatible", ProviderRegistration{
+		Factory: func() (Provider, error) {
+			return NewOpenAICompatibleProvider()
+		},
+		IsConfigured: egoUtils.IsOpenAICompatibleConfigured,
+		Priority:     400,
+	})
+}
+
+// NewOpenAICompatibleProvider creates a new OpenAICompatibleProvider instance
+// from the `OPENAI_COMPATIBLE_BASE_URL` and the optional
+// `OPENAI_COMPATIBLE_API_KEY`, `OPENAI_COMPATIBLE_IMAGES` and
+// `OPENAI_COMPATIBLE_MODEL` environment variables.
+func NewOpenAICompatibleProvider() (*OpenAICompatibleProvider, error) {
+	baseUrl := strings.TrimSpace(os.Getenv("OPENAI_COMPATIBLE_BASE_URL"))
+	if baseUrl == "" {
+		return nil, errors.New("no OpenAI compatible base URL defined")
+	}
+
+	return &OpenAICompatibleProvider{
+		ApiKey:      strings.TrimSpace(os.Getenv("OPENAI_COMPATIBLE_API_KEY")),
+		BaseUrl:     baseUrl,
+		ImagesModel: egoUtils.IsTruthy(os.Getenv("OPENAI_COMPATIBLE_IMAGES")),
+		Model:       strings.TrimSpace(os.Getenv("OPENAI_COMPATIBLE_MODEL")),
+	}, nil
+}
+
+// Complete implements Provider.Complete().
+func (p *OpenAICompatibleProvider) Complete(ctx context.Context, request CompletionRequest) (CompletionResponse, error) {
+	request, err := p.withModel(request)
+	if err != nil {
+		return CompletionResponse{}, err
+	}
+
+	return completeChatGPTOpenAI(ctx, p.Name(), request, p.sendRequest, p.getFeatures())
+}
+
+// CompleteStream implements StreamingProvider.CompleteStream().
+func (p *OpenAICompatibleProvider) CompleteStream(ctx context.Context, request CompletionRequest, onToken TokenHandler) (CompletionResponse, error) {
+	request, err := p.withModel(request)
+	if err != nil {
+		return CompletionResponse{}, err
+	}
+
+	return completeChatGPTOpenAIStream(ctx, p.Name(), request, p.sendRequest, p.getFeatures(), onToken)
+}
+
+// DefaultModel implements ModelProvider.DefaultModel().
+func (p *OpenAICompatibleProvider) DefaultModel(request CompletionRequest) string {
+	return p.Model
+}
+
+// Name implements Provider.Name().
+func (p *OpenAICompatibleProvider) Name() string {
+	return "openai-compatible"
+}
+
+// SupportsImages implements ImageProvider.SupportsImages().
+func (p *OpenAICompatibleProvider) SupportsImages() bool {
+	// most local models do not support images
+	return p.ImagesModel
+}
+
+// SupportsTools implements ToolProvider.SupportsTools().
+func (p *OpenAICompatibleProvider) SupportsTools() bool {
+	return true
+}
+
+func (p *OpenAICompatibleProvider) getFeatures() chatGPTOpenAIFeatures {
+	// not all servers support `json_schema` and `stream_options`
+	return chatGPTOpenAIFeatures{
+		jsonSchema:  false,
+		streamUsage: false,
+	}
+}
+
+func (p *OpenAICompatibleProvider) sendRequest(ctx context.Context, payload ChatGPTOpenAIRequestBody) (*http.Response, error) {
+	url := getChatCompletionsUrl(p.BaseUrl)
+
+	return sendChatGPTOpenAIRequest(ctx, url, payload, func(chatRequest *http.Request) {
+		if p.ApiKey != "" {
+			chatRequest.Header.Set("Authorization", fmt.Sprintf("Bearer %v", p.ApiKey))
+		}
+	})
+}
+
+// withModel sets the model of `request` to the default one of `p`, if it is
+// not defined, because there is no model, which all servers know
+func (p *OpenAICompatibleProvider) withModel(request CompletionRequest) (CompletionRequest, error) {
+	if request.Model == "" {
+		request.Model = p.Model
+	}
+	if request.Model == "" {
+		return request, errors.New("no model for OpenAI compatible API defined, set OPENAI_COMPATIBLE_MODEL")
+	}
+
+	return request, nil
+}
@@ -0,0 +1,182 @@
+// This file is part of the e.GPT distribution.
+// Copyright (c) Next.e.GO Mobile SE, Aachen, Germany (https://e-go-mobile.com/)
+//
+// e-gpt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, version 3.
+//
+// e-gpt is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	egoUtils "github.com/egomobile/e-gpt/utils"
+)
+
+// ApiProxyProvider is a Provider, which uses a simplified and more generic
+// chat REST API, authorized by an API key or OAuth 2.
+type ApiProxyProvider struct {
+	ApiKey       string // The optional API key
+	ApiKeyHeader string // The name of the HTTP header for `ApiKey`
+	ApiUrl       string // The URL of the chat API
+	ClientId     string // The client ID
+}
+
+func init() {
+	RegisterProvider("proxy", ProviderRegistration{
+		Factory: func() (Provider, error) {
+			return NewApiProxyProvider()
+		},
+		IsConfigured: func() bool {
+			return strings.TrimSpace(os.Getenv("CHAT_API_CLIENT_ID")) != ""
+		},
+		Priority: 200,
+	})
+}
+
+// NewApiProxyProvider creates a new ApiProxyProvider instance from the
+// `CHAT_API_*` environment variables.
+func NewApiProxyProvider() (*ApiProxyProvider, error) {
+	clientId := strings.TrimSpace(os.Getenv("CHAT_API_CLIENT_ID"))
+	if clientId == "" {
+		return nil, errors.New("no API client ID defined")
+	}
+
+	apiUrl := strings.TrimSpace(os.Getenv("CHAT_API_URL"))
+	if apiUrl == "" {
+		return nil, errors.New("no API url defined")
+	}
+
+	apiKeyHeader := strings.TrimSpace(os.Getenv("CHAT_API_KEY_HEADER"))
+	if apiKeyHeader == "" {
+		apiKeyHeader = "x-api-key"
+	}
+
+	return &ApiProxyProvider{
+		ApiKey:       strings.TrimSpace(os.Getenv("CHAT_API_KEY")),
+		ApiKeyHeader: apiKeyHeader,
+		ApiUrl:       apiUrl,
+		ClientId:     clientId,
+	}, nil
+}
+
+// Complete implements Provider.Complete().
+func (p *ApiProxyProvider) Complete(ctx context.Context, request CompletionRequest) (CompletionResponse, error) {
+	var response CompletionResponse
+
+	clientId := p.ClientId
+	authHeader := p.ApiKeyHeader
+	authValue := p.ApiKey
+	if authValue == "" {
+		accessTokenResponse, err := egoUtils.GetAccessToken(ctx)
+		if err != nil {
+			return response, err
+		}
+
+		clientId = ""
+		authHeader = "Authorization"
+		authValue = fmt.Sprintf("Bearer %v", accessTokenResponse.AccessToken)
+	}
+
+	conversation := make([]string, 0, len(request.Messages))
+	for i, message := range request.Messages {
+		expectedRole := "user"
+		if i%2 == 1 {
+			expectedRole = "assistant"
+		}
+
+		if message.Role != expectedRole {
+			return response, fmt.Errorf("message %v must be from %v", i, expectedRole)
+		}
+
+		conversation = append(conversation, message.Content)
+	}
+
+	payload := ChatApiRequestBody{
+		Conversation:     conversation,
+		FrequencyPenalty: request.FrequencyPenalty,
+		MaxTokens:        request.MaxTokens,
+		Model:            request.Model,
+		PresencePenalty:  request.PresencePenalty,
+		Stop:             request.Stop,
+		SystemPrompt:     request.SystemPrompt,
+		Temperature:      request.Temperature,
+		TopP:             request.TopP,
+	}
+
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return response, err
+	}
+
+	chatResponse, err := egoUtils.SendHttpRequest(ctx, func(ctx context.Context) (*http.Request, error) {
+		chatRequest, err := http.NewRequestWithContext(ctx, "POST", p.ApiUrl, bytes.NewBuffer(payloadJSON))
+		if err != nil {
+			return nil, err
+		}
+
+		// set HTTP headers
+		chatRequest.Header.Set(authHeader, authValue)
+		if clientId != "" {
+			chatRequest.Header.Set("x-api-client", clientId)
+		}
+
+		return chatRequest, nil
+	})
+	if err != nil {
+		// request failed
+		return response, err
+	}
+
+	defer chatResponse.Body.Close()
+
+	if chatResponse.StatusCode != 200 {
+		// must be 200
+
+		if chatResponse.StatusCode == 401 && p.ApiKey == "" {
+			// cached access token may have been revoked
+			egoUtils.ClearAccessTokenCache()
+		}
+
+		return response, egoUtils.NewResponseError("unexpected response: ", chatResponse)
+	}
+
+	bodyData, err := io.ReadAll(chatResponse.Body)
+	if err != nil {
+		// could not read response body
+		return response, err
+	}
+
+	var chatResponseBody ChatApiResponseBody
+	err = json.Unmarshal(bodyData, &chatResponseBody)
+	if err != nil {
+		// could not parse JSON in `bodyData`
+		return response, err
+	}
+
+	response.Answer = chatResponseBody.Data.Answer
+	response.Provider = p.Name()
+
+	return response, nil
+}
+
+// Name implements Provider.Name().
+func (p *ApiProxyProvider) Name() string {
+	return "proxy"
+}
@@ -0,0 +1,91 @@
+// This file is part of the e.GPT distribution.
+// Copyright (c) Next.e.GO Mobile SE, Aachen, Germany (https://e-go-mobile.com/)
+//
+// e-gpt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, version 3.
+//
+// e-gpt is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestConversationToMessages(t *testing.T) {
+	messages := ConversationToMessages("question 1", "answer 1", "question 2")
+
+	expected := []Message{
+		{Content: "question 1", Role: "user"},
+		{Content: "answer 1", Role: "assistant"},
+		{Content: "question 2", Role: "user"},
+	}
+
+	if len(messages) != len(expected) {
+		t.Fatalf("expected %v messages, got %v", len(expected), len(messages))
+	}
+	for i, message := range messages {
+		if message.Content != expected[i].Content || message.Role != expected[i].Role {
+			t.Errorf("message %v is %+v; expected %+v", i, message, expected[i])
+		}
+	}
+}
+
+func TestConversationToMessagesWithoutConversation(t *testing.T) {
+	messages := ConversationToMessages()
+	if messages == nil || len(messages) != 0 {
+		t.Fatalf("expected empty list, got %#v", messages)
+	}
+}
+
+func TestOpenAICompatibleProvider(t *testing.T) {
+	var model string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body ChatGPTOpenAIRequestBody
+		json.NewDecoder(r.Body).Decode(&body)
+		model = body.Model
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("OPENAI_BASE_URL", "http://127.0.0.1:1/v1")
+	t.Setenv("OPENAI_COMPATIBLE_API_KEY", "")
+	t.Setenv("OPENAI_COMPATIBLE_BASE_URL", server.URL)
+	t.Setenv("OPENAI_COMPATIBLE_MODEL", "llama3.1")
+
+	provider, err := NewOpenAICompatibleProvider()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := CompletionRequest{
+		Messages: ConversationToMessages("question"),
+	}
+
+	_, err = provider.Complete(context.Background(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if model != "llama3.1" {
+		t.Errorf("expected model llama3.1, got %v", model)
+	}
+
+	provider.Model = ""
+	_, err = provider.Complete(context.Background(), request)
+	if err == nil {
+		t.Error("expected error without model")
+	}
+}
@@ -0,0 +1,129 @@
+// This file is part of the e.GPT distribution.
+// Copyright (c) Next.e.GO Mobile SE, Aachen, Germany (https://e-go-mobile.com/)
+//
+// e-gpt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, version 3.
+//
+// e-gpt is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package openai
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"strings"
+)
+
+// StreamingProvider is a Provider, which is also able to send parts of
+// an answer, as soon as they arrive.
+type StreamingProvider interface {
+	Provider
+
+	// CompleteStream sends `request` to the backend, invokes `onToken` for each
+	// part of the answer and finally returns the complete answer.
+	CompleteStream(ctx context.Context, request CompletionRequest, onToken TokenHandler) (CompletionResponse, error)
+}
+
+// TokenHandler is invoked for each part of a streamed answer.
+type TokenHandler func(token string) error
+
+// CompleteStream works like Complete(), but invokes `onToken` for each part
+// of the answer. If the provider is no StreamingProvider, `onToken` is
+// invoked once with the complete answer. The next provider of a list is
+// only used, if `onToken` has not been invoked yet.
+func CompleteStream(ctx context.Context, request CompletionRequest, onToken TokenHandler) (CompletionResponse, error) {
+	request, providers, err := prepareCompletion(request)
+	if err != nil {
+		return CompletionResponse{}, err
+	}
+
+	hasOutput := false
+	handleToken := func(token string) error {
+		hasOutput = true
+
+		return onToken(token)
+	}
+
+	response, err := completeWithFallback(ctx, request, providers, func(ctx context.Context, provider Provider, request CompletionRequest) (CompletionResponse, error) {
+		streamingProvider, ok := provider.(StreamingProvider)
+		if ok {
+			return streamingProvider.CompleteStream(ctx, request, handleToken)
+		}
+
+		response, err := provider.Complete(ctx, request)
+		if err == nil && response.Answer != "" {
+			err = handleToken(response.Answer)
+		}
+
+		return response, err
+	}, func() bool {
+		return !hasOutput
+	})
+	if err == nil && response.Cached {
+		err = handleToken(response.Answer)
+	}
+
+	return response, err
+}
+
+// readServerSentEvents reads `reader` as stream of server-sent events
+// and invokes `onData` with the data of each event. The function stops
+// after the end of the stream or, if `onData` returns `false` or an error.
+func readServerSentEvents(reader io.Reader, onData func(data string) (bool, error)) error {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+
+	var dataLines []string
+
+	dispatch := func() (bool, error) {
+		if len(dataLines) == 0 {
+			return true, nil
+		}
+
+		data := strings.Join(dataLines, "\n")
+		dataLines = nil
+
+		return onData(data)
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if line == "" {
+			// empty line dispatches the event
+			shouldContinue, err := dispatch()
+			if err != nil || !shouldContinue {
+				return err
+			}
+
+			continue
+		}
+
+		if strings.HasPrefix(line, ":") {
+			continue // comment
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+
+		if field == "data" {
+			dataLines = append(dataLines, value)
+		}
+	}
+
+	err := scanner.Err()
+	if err != nil {
+		return err
+	}
+
+	_, err = dispatch()
+	return err
+}
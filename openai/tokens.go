@@ -0,0 +1,177 @@
+// This file is part of the e.GPT distribution.
+// Copyright (c) Next.e.GO Mobile SE, Aachen, Germany (https://e-go-mobile.com/)
+//
+// e-gpt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, version 3.
+//
+// e-gpt is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package openai
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkoukk/tiktoken-go"
+	tiktoken_loader "github.com/pkoukk/tiktoken-go-loader"
+)
+
+const defaultContextLimit = 4096
+const defaultMaxTokens = 2048
+const defaultModel = "gpt-3.5-turbo"
+const defaultVisionModel = "gpt-4o"
+
+// s. https://github.com/openai/openai-cookbook/blob/main/examples/How_to_count_tokens_with_tiktoken.ipynb
+const tokensPerMessage = 3
+const tokensPerReply = 3
+
+var tokenEncoding *tiktoken.Tiktoken
+var tokenEncodingErr error
+var tokenEncodingOnce sync.Once
+
+// CountTokens returns the number of tokens of `text`, using the
+// cl100k_base encoding of GPT-3.5 and GPT-4 models.
+func CountTokens(text string) (int, error) {
+	encoding, err := getTokenEncoding()
+	if err != nil {
+		return 0, err
+	}
+
+	return len(encoding.EncodeOrdinary(text)), nil
+}
+
+// CountRequestTokens returns the number of prompt tokens, which are required
+// by the system prompt and the messages of `request`.
+func CountRequestTokens(request CompletionRequest) (int, error) {
+	count, err := countMessageTokens("system", request.SystemPrompt)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, message := range request.Messages {
+		messageCount, err := countMessageTokens(message.Role, message.Content)
+		if err != nil {
+			return 0, err
+		}
+
+		count += messageCount + len(message.Images)*tokensPerImage
+
+		for _, toolCall := range message.ToolCalls {
+			toolCallCount, err := CountTokens(toolCall.Name + toolCall.Arguments)
+			if err != nil {
+				return 0, err
+			}
+
+			count += toolCallCount
+		}
+	}
+
+	return count + tokensPerReply, nil
+}
+
+// GetContextLimit returns the maximum number of tokens, `model` can handle for
+// prompt and answer. The value can be overwritten by CHAT_API_CONTEXT_LIMIT.
+func GetContextLimit(model string) int {
+	limit, ok := LookupContextLimit(model)
+	if !ok {
+		return defaultContextLimit
+	}
+
+	return limit
+}
+
+// LookupContextLimit returns the context limit of `model`, which can be
+// overwritten by CHAT_API_CONTEXT_LIMIT, and `false`, if it is unknown.
+func LookupContextLimit(model string) (int, bool) {
+	str := strings.TrimSpace(os.Getenv("CHAT_API_CONTEXT_LIMIT"))
+	if str != "" {
+		val, err := strconv.Atoi(str)
+		if err == nil && val > 0 {
+			return val, true
+		}
+	}
+
+	if strings.TrimSpace(model) == "" {
+		return 0, false
+	}
+
+	return getByModelPrefix(model, modelContextLimits)
+}
+
+func countMessageTokens(role string, content string) (int, error) {
+	roleCount, err := CountTokens(role)
+	if err != nil {
+		return 0, err
+	}
+
+	contentCount, err := CountTokens(content)
+	if err != nil {
+		return 0, err
+	}
+
+	return tokensPerMessage + roleCount + contentCount, nil
+}
+
+func getTokenEncoding() (*tiktoken.Tiktoken, error) {
+	tokenEncodingOnce.Do(func() {
+		// use embedded files instead of downloading them
+		tiktoken.SetBpeLoader(tiktoken_loader.NewOfflineLoader())
+
+		tokenEncoding, tokenEncodingErr = tiktoken.GetEncoding("cl100k_base")
+	})
+
+	return tokenEncoding, tokenEncodingErr
+}
+
+// trimMessagesToContextLimit removes the oldest turns of `request`, until the
+// system prompt, the messages and the maximum number of tokens of the answer
+// fit into the context of `model`. Turns start with a message of the user, so
+// tool calls are never separated from their results, and the last turn is
+// always kept. If the context limit of `model` is unknown, the messages are
+// not trimmed.
+func trimMessagesToContextLimit(request CompletionRequest, model string) ([]Message, error) {
+	contextLimit, ok := LookupContextLimit(model)
+	if !ok {
+		return request.Messages, nil
+	}
+
+	maxTokens := int(request.MaxTokens)
+	if maxTokens < 1 {
+		maxTokens = defaultMaxTokens
+	}
+
+	availableTokens := contextLimit - maxTokens
+
+	messages := request.Messages
+	for {
+		request.Messages = messages
+
+		count, err := CountRequestTokens(request)
+		if err != nil {
+			return nil, err
+		}
+
+		if count <= availableTokens {
+			break
+		}
+
+		// remove oldest turn, conversations have to start with a user message
+		nextTurn := getNextTurn(messages)
+		if nextTurn >= len(messages) {
+			break // last turn
+		}
+
+		messages = messages[nextTurn:]
+	}
+
+	return messages, nil
+}
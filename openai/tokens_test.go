@@ -0,0 +1,187 @@
+// This file is part of the e.GPT distribution.
+// Copyright (c) Next.e.GO Mobile SE, Aachen, Germany (https://e-go-mobile.com/)
+//
+// e-gpt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, version 3.
+//
+// e-gpt is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package openai
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type testModelProvider struct {
+	model string
+}
+
+func (p *testModelProvider) Complete(ctx context.Context, request CompletionRequest) (CompletionResponse, error) {
+	return CompletionResponse{}, nil
+}
+
+func (p *testModelProvider) DefaultModel(request CompletionRequest) string {
+	return p.model
+}
+
+func (p *testModelProvider) Name() string {
+	return "test"
+}
+
+func TestLookupContextLimit(t *testing.T) {
+	t.Setenv("CHAT_API_CONTEXT_LIMIT", "")
+
+	tests := []struct {
+		model string
+		limit int
+		ok    bool
+	}{
+		{"", 0, false},
+		{"unknown-model", 0, false},
+		{"gpt-3.5-turbo", 16385, true},
+		{"gpt-35-turbo", 16385, true},
+		{"gpt-3.5-turbo-instruct", 4096, true},
+		{"gpt-4", 8192, true},
+		{"gpt-4-0613", 8192, true},
+		{"gpt-4-32k-0613", 32768, true},
+		{"gpt-4.1", 1047576, true},
+		{"gpt-4.1-mini-2025-04-14", 1047576, true},
+		{"gpt-4.5-preview", 0, false},
+		{"gpt-4o-mini", 128000, true},
+		{"gpt-5-mini", 400000, true},
+		{"gpt-5-chat-latest", 128000, true},
+		{"o1", 200000, true},
+		{"o1-mini", 128000, true},
+		{"o3-mini", 200000, true},
+		{"o4-mini", 200000, true},
+		{"claude-3-5-sonnet-latest", 200000, true},
+	}
+
+	for _, test := range tests {
+		limit, ok := LookupContextLimit(test.model)
+		if limit != test.limit || ok != test.ok {
+			t.Errorf("LookupContextLimit(%q) = %v, %v; expected %v, %v", test.model, limit, ok, test.limit, test.ok)
+		}
+	}
+}
+
+func TestLookupContextLimitFromEnv(t *testing.T) {
+	t.Setenv("CHAT_API_CONTEXT_LIMIT", "1000")
+
+	for _, model := range []string{"", "unknown-model", "gpt-4"} {
+		limit, ok := LookupContextLimit(model)
+		if limit != 1000 || !ok {
+			t.Errorf("LookupContextLimit(%q) = %v, %v; expected 1000, true", model, limit, ok)
+		}
+	}
+}
+
+func TestGetContextLimitOfUnknownModel(t *testing.T) {
+	t.Setenv("CHAT_API_CONTEXT_LIMIT", "")
+
+	limit := GetContextLimit("unknown-model")
+	if limit != defaultContextLimit {
+		t.Fatalf("expected %v, got %v", defaultContextLimit, limit)
+	}
+}
+
+func TestGetRequestModel(t *testing.T) {
+	provider := &testModelProvider{model: "default-model"}
+
+	model := getRequestModel(provider, CompletionRequest{Model: "custom-model"})
+	if model != "custom-model" {
+		t.Errorf("expected model of request, got %q", model)
+	}
+
+	model = getRequestModel(provider, CompletionRequest{})
+	if model != "default-model" {
+		t.Errorf("expected default model of provider, got %q", model)
+	}
+}
+
+func TestTrimMessagesToContextLimit(t *testing.T) {
+	text := strings.Repeat("word ", 100)
+
+	request := CompletionRequest{
+		MaxTokens: 100,
+		Messages:  ConversationToMessages("first "+text, text, "second "+text, text, "third "+text),
+	}
+
+	// the last turn and one more fit into the context
+	lastTurnsRequest := request
+	lastTurnsRequest.Messages = request.Messages[2:]
+	count, err := CountRequestTokens(lastTurnsRequest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("CHAT_API_CONTEXT_LIMIT", "")
+	messages, err := trimMessagesToContextLimit(request, "unknown-model")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 5 {
+		t.Fatalf("messages of unknown models must not be trimmed, got %v", len(messages))
+	}
+
+	t.Setenv("CHAT_API_CONTEXT_LIMIT", strconv.Itoa(count+int(request.MaxTokens)))
+	messages, err = trimMessagesToContextLimit(request, "gpt-4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 3 || !strings.HasPrefix(messages[0].Content, "second") {
+		t.Fatalf("expected last 3 messages, got %v", len(messages))
+	}
+
+	t.Setenv("CHAT_API_CONTEXT_LIMIT", "10")
+	messages, err = trimMessagesToContextLimit(request, "gpt-4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 1 || !strings.HasPrefix(messages[0].Content, "third") {
+		t.Fatalf("expected last message, got %v", len(messages))
+	}
+}
+
+func TestTrimMessagesToContextLimitKeepsToolCalls(t *testing.T) {
+	t.Setenv("CHAT_API_CONTEXT_LIMIT", "10")
+
+	text := strings.Repeat("word ", 100)
+
+	toolMessages := []Message{
+		{Content: "second " + text, Role: "user"},
+		{Role: "assistant", ToolCalls: []ToolCall{{Arguments: "{}", Id: "call_1", Name: "tool"}}},
+		{Content: text, Role: "tool", ToolCallId: "call_1"},
+		{Role: "assistant", ToolCalls: []ToolCall{{Arguments: "{}", Id: "call_2", Name: "tool"}}},
+		{Content: text, Role: "tool", ToolCallId: "call_2"},
+	}
+
+	request := CompletionRequest{
+		Messages: append(ConversationToMessages("first "+text, text), toolMessages...),
+	}
+
+	messages, err := trimMessagesToContextLimit(request, "gpt-4")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the last turn is kept completely, even if it is too long
+	if len(messages) != len(toolMessages) {
+		t.Fatalf("expected %v messages, got %v", len(toolMessages), len(messages))
+	}
+	for i, message := range messages {
+		if message.Role != toolMessages[i].Role || message.Content != toolMessages[i].Content {
+			t.Fatalf("message %v is %+v; expected %+v", i, message, toolMessages[i])
+		}
+	}
+}
@@ -0,0 +1,212 @@
+// This file is part of the e.GPT distribution.
+// Copyright (c) Next.e.GO Mobile SE, Aachen, Germany (https://e-go-mobile.com/)
+//
+// e-gpt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, version 3.
+//
+// e-gpt is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	egoUtils "github.com/egomobile/e-gpt/utils"
+)
+
+const maxToolRounds = 10
+
+// Tool describes a function, which can be called by the model.
+type Tool struct {
+	Description string          `json:"description,omitempty"` // Description, which helps the model to decide when to call the tool
+	Name        string          `json:"name"`                  // Unique name of the tool
+	Parameters  json.RawMessage `json:"parameters,omitempty"`  // Parameters of the tool as JSON schema
+}
+
+// ToolCall represents a call of a Tool by the model.
+type ToolCall struct {
+	Arguments string `json:"arguments"` // Arguments as JSON string
+	Id        string `json:"id"`        // ID of the call, which has to be submitted with the result
+	Name      string `json:"name"`      // Name of the tool
+}
+
+// ToolHandler executes a tool with the JSON encoded `arguments` and returns
+// the result, which is sent back to the model.
+type ToolHandler func(ctx context.Context, arguments string) (string, error)
+
+// ToolProvider is a Provider, which is able to handle the Tools
+// of a CompletionRequest and to return ToolCalls.
+type ToolProvider interface {
+	Provider
+
+	// SupportsTools returns `true`, if the backend is able to call tools.
+	SupportsTools() bool
+}
+
+// ToolRegistry stores tools and the handlers, which execute them.
+type ToolRegistry struct {
+	handlers map[string]ToolHandler
+	lock     sync.RWMutex
+	tools    map[string]Tool
+}
+
+// NewToolRegistry creates a new, empty ToolRegistry instance.
+func NewToolRegistry() *ToolRegistry {
+	return &ToolRegistry{
+		handlers: make(map[string]ToolHandler),
+		tools:    make(map[string]Tool),
+	}
+}
+
+// CompleteWithTools works like Complete(), but offers all tools of `registry`
+// to the model and executes the tool calls of the model, until it sends the
+// final answer. Errors of tools are sent to the model as result.
+func CompleteWithTools(ctx context.Context, request CompletionRequest, registry *ToolRegistry) (CompletionResponse, error) {
+	request.Messages = append([]Message{}, request.Messages...)
+	request.Tools = append(append([]Tool{}, request.Tools...), registry.Tools()...)
+
+	for round := 0; ; round++ {
+		response, err := Complete(ctx, request)
+		if err != nil {
+			return response, err
+		}
+
+		if len(response.ToolCalls) == 0 {
+			return response, nil
+		}
+
+		if round >= maxToolRounds {
+			return response, fmt.Errorf("no answer after %v rounds of tool calls", maxToolRounds)
+		}
+
+		request.Messages = append(request.Messages, Message{
+			Content:   response.Answer,
+			Role:      "assistant",
+			ToolCalls: response.ToolCalls,
+		})
+
+		for _, toolCall := range response.ToolCalls {
+			egoUtils.LogVerbose("calling tool %v with %v", toolCall.Name, toolCall.Arguments)
+
+			result, err := registry.Execute(ctx, toolCall)
+			if err != nil {
+				if ctx.Err() != nil {
+					return response, err
+				}
+
+				result = fmt.Sprintf("ERROR: %v", err.Error())
+			}
+
+			request.Messages = append(request.Messages, Message{
+				Content:    result,
+				Role:       "tool",
+				ToolCallId: toolCall.Id,
+			})
+		}
+
+		// the model should decide by itself after the first round
+		if request.ToolChoice != "none" {
+			request.ToolChoice = ""
+		}
+	}
+}
+
+// Execute runs the handler of the tool, which is called by `toolCall`.
+func (r *ToolRegistry) Execute(ctx context.Context, toolCall ToolCall) (string, error) {
+	r.lock.RLock()
+	handler, ok := r.handlers[toolCall.Name]
+	r.lock.RUnlock()
+
+	if !ok {
+		return "", fmt.Errorf("tool %v not found", toolCall.Name)
+	}
+
+	arguments := strings.TrimSpace(toolCall.Arguments)
+	if arguments == "" {
+		arguments = "{}"
+	}
+
+	return handler(ctx, arguments)
+}
+
+// Register registers `handler` for `tool`. An existing tool with the
+// same name will be replaced.
+func (r *ToolRegistry) Register(tool Tool, handler ToolHandler) error {
+	tool.Name = strings.TrimSpace(tool.Name)
+	if tool.Name == "" {
+		return errors.New("name of tool must not be empty")
+	}
+
+	if handler == nil {
+		return errors.New("handler of tool must not be nil")
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.handlers[tool.Name] = handler
+	r.tools[tool.Name] = tool
+
+	return nil
+}
+
+// Tools returns all registered tools, sorted by name.
+func (r *ToolRegistry) Tools() []Tool {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	tools := make([]Tool, 0, len(r.tools))
+	for _, tool := range r.tools {
+		tools = append(tools, tool)
+	}
+
+	sort.Slice(tools, func(x, y int) bool {
+		return tools[x].Name < tools[y].Name
+	})
+
+	return tools
+}
+
+// getToolProviders returns the items of `providers`, which support tools
+func getToolProviders(providers []Provider) ([]Provider, error) {
+	var toolProviders []Provider
+	for _, provider := range providers {
+		toolProvider, ok := provider.(ToolProvider)
+		if ok && toolProvider.SupportsTools() {
+			toolProviders = append(toolProviders, provider)
+		} else {
+			egoUtils.LogVerbose("skipping provider %v: tools are not supported", provider.Name())
+		}
+	}
+
+	if len(toolProviders) == 0 {
+		return nil, fmt.Errorf("provider %v does not support tools", providers[0].Name())
+	}
+
+	return toolProviders, nil
+}
+
+// hasToolMessages returns `true`, if `messages` contain tool calls or results,
+// which means, that user and assistant messages do not alternate
+func hasToolMessages(messages []Message) bool {
+	for _, message := range messages {
+		if message.Role == "tool" || len(message.ToolCalls) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
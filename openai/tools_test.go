@@ -0,0 +1,227 @@
+// This file is part of the e.GPT distribution.
+// Copyright (c) Next.e.GO Mobile SE, Aachen, Germany (https://e-go-mobile.com/)
+//
+// e-gpt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, version 3.
+//
+// e-gpt is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package openai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// testToolProvider is a ToolProvider, which calls the `add` tool in
+// `toolRounds` rounds, before it answers
+type testToolProvider struct {
+	requests   []CompletionRequest
+	toolRounds int
+}
+
+func (p *testToolProvider) Complete(ctx context.Context, request CompletionRequest) (CompletionResponse, error) {
+	p.requests = append(p.requests, request)
+
+	round := len(p.requests)
+	if p.toolRounds < 0 || round <= p.toolRounds {
+		return CompletionResponse{
+			ToolCalls: []ToolCall{
+				{Arguments: `{"a":1,"b":2}`, Id: fmt.Sprintf("call_%v", round), Name: "add"},
+			},
+		}, nil
+	}
+
+	last := request.Messages[len(request.Messages)-1]
+
+	return CompletionResponse{Answer: "result is " + last.Content}, nil
+}
+
+func (p *testToolProvider) Name() string {
+	return "test-tools"
+}
+
+func (p *testToolProvider) SupportsTools() bool {
+	return true
+}
+
+func registerTestToolProvider(t *testing.T, toolRounds int) *testToolProvider {
+	t.Setenv("CHAT_CACHE", "false")
+	t.Setenv("CHAT_USAGE_LEDGER", "false")
+
+	provider := &testToolProvider{toolRounds: toolRounds}
+	err := RegisterProvider(provider.Name(), ProviderRegistration{
+		Factory: func() (Provider, error) {
+			return provider, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return provider
+}
+
+func newTestToolRegistry(t *testing.T, handler ToolHandler) *ToolRegistry {
+	registry := NewToolRegistry()
+
+	err := registry.Register(Tool{Name: "add"}, handler)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return registry
+}
+
+func TestCompleteWithTools(t *testing.T) {
+	provider := registerTestToolProvider(t, 1)
+
+	var arguments string
+	registry := newTestToolRegistry(t, func(ctx context.Context, args string) (string, error) {
+		arguments = args
+		return "3", nil
+	})
+
+	request := CompletionRequest{
+		Messages: ConversationToMessages("what is 1 + 2?"),
+		Provider: provider.Name(),
+	}
+
+	response, err := CompleteWithTools(context.Background(), request, registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Answer != "result is 3" {
+		t.Fatalf("unexpected answer %q", response.Answer)
+	}
+	if arguments != `{"a":1,"b":2}` {
+		t.Fatalf("unexpected arguments %q", arguments)
+	}
+
+	if len(provider.requests) != 2 {
+		t.Fatalf("expected 2 requests, got %v", len(provider.requests))
+	}
+	if len(provider.requests[0].Tools) != 1 || provider.requests[0].Tools[0].Name != "add" {
+		t.Fatalf("tools are not offered: %+v", provider.requests[0].Tools)
+	}
+
+	messages := provider.requests[1].Messages
+	if len(messages) != 3 {
+		t.Fatalf("expected 3 messages, got %+v", messages)
+	}
+	if messages[1].Role != "assistant" || len(messages[1].ToolCalls) != 1 {
+		t.Fatalf("tool calls are not sent back: %+v", messages[1])
+	}
+	if messages[2].Role != "tool" || messages[2].Content != "3" || messages[2].ToolCallId != "call_1" {
+		t.Fatalf("tool result is not sent back: %+v", messages[2])
+	}
+}
+
+func TestCompleteWithToolsSendsErrorsAsResult(t *testing.T) {
+	provider := registerTestToolProvider(t, 1)
+
+	registry := newTestToolRegistry(t, func(ctx context.Context, args string) (string, error) {
+		return "", errors.New("division by zero")
+	})
+
+	request := CompletionRequest{
+		Messages: ConversationToMessages("what is 1 + 2?"),
+		Provider: provider.Name(),
+	}
+
+	response, err := CompleteWithTools(context.Background(), request, registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Answer != "result is ERROR: division by zero" {
+		t.Fatalf("unexpected answer %q", response.Answer)
+	}
+}
+
+func TestCompleteWithToolsStopsAfterMaxRounds(t *testing.T) {
+	provider := registerTestToolProvider(t, -1)
+
+	registry := newTestToolRegistry(t, func(ctx context.Context, args string) (string, error) {
+		return "3", nil
+	})
+
+	request := CompletionRequest{
+		Messages: ConversationToMessages("what is 1 + 2?"),
+		Provider: provider.Name(),
+	}
+
+	_, err := CompleteWithTools(context.Background(), request, registry)
+	if err == nil || !strings.Contains(err.Error(), "rounds of tool calls") {
+		t.Fatalf("expected error after max rounds, got %v", err)
+	}
+	if len(provider.requests) != maxToolRounds+1 {
+		t.Fatalf("expected %v requests, got %v", maxToolRounds+1, len(provider.requests))
+	}
+}
+
+func TestCompleteWithToolsKeepsCurrentTurn(t *testing.T) {
+	t.Setenv("CHAT_MAX_CONVERSATION_SIZE", "4")
+
+	provider := registerTestToolProvider(t, 5)
+
+	registry := newTestToolRegistry(t, func(ctx context.Context, args string) (string, error) {
+		return "3", nil
+	})
+
+	request := CompletionRequest{
+		Messages: ConversationToMessages("first question", "first answer", "what is 1 + 2?"),
+		Provider: provider.Name(),
+	}
+
+	response, err := CompleteWithTools(context.Background(), request, registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Answer != "result is 3" {
+		t.Fatalf("unexpected answer %q", response.Answer)
+	}
+
+	if len(provider.requests[0].Messages) != 3 {
+		t.Fatalf("first request must not be trimmed: %+v", provider.requests[0].Messages)
+	}
+
+	// the older turn is removed, but no part of the current one
+	for i, providerRequest := range provider.requests[1:] {
+		messages := providerRequest.Messages
+		if messages[0].Role != "user" || messages[0].Content != "what is 1 + 2?" {
+			t.Fatalf("request %v does not start with the current question: %+v", i+1, messages[0])
+		}
+		if len(messages) != 3+2*i {
+			t.Fatalf("request %v has %v messages; expected %v", i+1, len(messages), 3+2*i)
+		}
+	}
+}
+
+func TestTrimMessages(t *testing.T) {
+	messages := ConversationToMessages("question 1", "answer 1", "question 2", "answer 2", "question 3")
+
+	trimmed := trimMessages(messages, 4)
+	if len(trimmed) != 3 || trimmed[0].Content != "question 2" {
+		t.Fatalf("unexpected messages %+v", trimmed)
+	}
+
+	trimmed = trimMessages(messages, 1)
+	if len(trimmed) != 1 || trimmed[0].Content != "question 3" {
+		t.Fatalf("unexpected messages %+v", trimmed)
+	}
+
+	trimmed = trimMessages(messages[1:], 40)
+	if len(trimmed) != 3 || trimmed[0].Content != "question 2" {
+		t.Fatalf("conversation must start with a user message: %+v", trimmed)
+	}
+}
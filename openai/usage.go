@@ -0,0 +1,110 @@
+// This file is part of the e.GPT distribution.
+// Copyright (c) Next.e.GO Mobile SE, Aachen, Germany (https://e-go-mobile.com/)
+//
+// e-gpt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, version 3.
+//
+// e-gpt is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package openai
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	egoUtils "github.com/egomobile/e-gpt/utils"
+)
+
+// EstimateCost returns the estimated cost in USD of `usage` with `model`
+// and `false`, if the model or its price is unknown. Prices can be overwritten
+// by CHAT_API_PROMPT_PRICE and CHAT_API_COMPLETION_PRICE.
+func EstimateCost(model string, usage CompletionUsage) (float64, bool) {
+	var price modelPrice
+	ok := false
+	if strings.TrimSpace(model) != "" {
+		price, ok = getByModelPrefix(model, modelPrices)
+	}
+
+	var hasPromptPrice, hasCompletionPrice bool
+	price.Prompt, hasPromptPrice = getPriceFromEnv("CHAT_API_PROMPT_PRICE", price.Prompt)
+	price.Completion, hasCompletionPrice = getPriceFromEnv("CHAT_API_COMPLETION_PRICE", price.Completion)
+
+	if !ok && !hasPromptPrice && !hasCompletionPrice {
+		return 0, false
+	}
+
+	return float64(usage.PromptTokens)/1000*price.Prompt +
+		float64(usage.CompletionTokens)/1000*price.Completion, true
+}
+
+func getPriceFromEnv(name string, defaultPrice float64) (float64, bool) {
+	str := strings.TrimSpace(os.Getenv(name))
+	if str != "" {
+		val, err := strconv.ParseFloat(str, 64)
+		if err == nil && val >= 0 {
+			return val, true
+		}
+	}
+
+	return defaultPrice, false
+}
+
+// recordUsage counts the tokens of `request` and `response` locally, if the
+// provider did not send them, and appends them to the usage ledger
+func recordUsage(request CompletionRequest, response *CompletionResponse) {
+	if response.Usage == nil {
+		promptTokens, err := CountRequestTokens(request)
+		if err != nil {
+			return
+		}
+
+		completionTokens, err := CountTokens(response.Answer)
+		if err != nil {
+			return
+		}
+
+		response.Usage = &CompletionUsage{
+			CompletionTokens: completionTokens,
+			Estimated:        true,
+			PromptTokens:     promptTokens,
+			TotalTokens:      promptTokens + completionTokens,
+		}
+	}
+
+	if !egoUtils.IsUsageLedgerEnabled() {
+		return
+	}
+
+	model := response.Model
+	if model == "" {
+		model = request.Model
+	}
+
+	var cost *float64
+	estimatedCost, ok := EstimateCost(model, *response.Usage)
+	if ok {
+		cost = &estimatedCost
+	}
+
+	// errors are ignored, because the ledger must not break API calls
+	egoUtils.AppendUsageEntry(egoUtils.UsageEntry{
+		Command:          egoUtils.GetCommandName(),
+		CompletionTokens: response.Usage.CompletionTokens,
+		Cost:             cost,
+		Estimated:        response.Usage.Estimated,
+		Model:            model,
+		PromptTokens:     response.Usage.PromptTokens,
+		Provider:         response.Provider,
+		Time:             time.Now(),
+		TotalTokens:      response.Usage.TotalTokens,
+	})
+}
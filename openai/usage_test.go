@@ -0,0 +1,58 @@
+// This file is part of the e.GPT distribution.
+// Copyright (c) Next.e.GO Mobile SE, Aachen, Germany (https://e-go-mobile.com/)
+//
+// e-gpt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, version 3.
+//
+// e-gpt is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package openai
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEstimateCost(t *testing.T) {
+	t.Setenv("CHAT_API_COMPLETION_PRICE", "")
+	t.Setenv("CHAT_API_PROMPT_PRICE", "")
+
+	usage := CompletionUsage{CompletionTokens: 1000, PromptTokens: 1000}
+
+	tests := []struct {
+		model string
+		cost  float64
+		ok    bool
+	}{
+		{"", 0, false},
+		{"llama3.1", 0, false},
+		{"gpt-4", 0.09, true},
+		{"gpt-4-0613", 0.09, true},
+		{"gpt-4.1", 0.01, true},
+		{"gpt-4.1-mini-2025-04-14", 0.002, true},
+		{"gpt-4.5-preview", 0, false},
+		{"gpt-4o-2024-08-06", 0.0125, true},
+		{"o3-mini", 0.0055, true},
+		{"claude-3-5-sonnet-latest", 0.018, true},
+	}
+
+	for _, test := range tests {
+		cost, ok := EstimateCost(test.model, usage)
+		if ok != test.ok || math.Abs(cost-test.cost) > 1e-9 {
+			t.Errorf("EstimateCost(%q) = %v, %v; expected %v, %v", test.model, cost, ok, test.cost, test.ok)
+		}
+	}
+
+	t.Setenv("CHAT_API_PROMPT_PRICE", "0.001")
+	cost, ok := EstimateCost("llama3.1", usage)
+	if !ok || math.Abs(cost-0.001) > 1e-9 {
+		t.Errorf("expected custom price for unknown model, got %v, %v", cost, ok)
+	}
+}
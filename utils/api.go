@@ -16,23 +16,174 @@
 package utils
 
 import (
+	"context"
 	"os"
 	"strings"
 )
 
+var chatProviderName string
+
 // GetApiAccessType returns the type of API access to be used.
-// It checks if OpenAI API Key is provided in environment variable OPENAI_API_KEY,
+// If providers have been selected by GetChatProviderNames(), only their settings are checked
+// and the access type of the first one, which is set up, is returned.
+// Otherwise it checks if OpenAI API Key is provided in environment variable OPENAI_API_KEY,
 // if not, it checks if Chat API Client ID is provided in environment variable CHAT_API_CLIENT_ID.
 // If Chat API Client ID is provided, it further checks if Chat API URL and Key are provided.
 // If Chat API URL and Key are provided, it returns "proxy_api_key".
 // If Chat API URL and Key are not provided, it gets the access token using GetAccessToken and returns "proxy_oauth2".
+// With the OAuth 2 device flow, no access token is requested, because this requires the user to sign in.
+// If no proxy API is set up, it checks if Azure OpenAI is set up by the AZURE_OPENAI_* variables and returns "azure_openai_key".
+// Then it checks if the base URL of an OpenAI compatible API is provided in OPENAI_COMPATIBLE_BASE_URL and returns "openai_compatible".
+// Finally it checks if Anthropic API Key is provided in ANTHROPIC_API_KEY and returns "anthropic_key".
 // If none of the above are provided, it returns an empty string and no error.
-func GetApiAccessType() (string, error) {
+func GetApiAccessType(ctx context.Context) (string, error) {
+	providerNames := GetChatProviderNames()
+	if len(providerNames) > 0 {
+		var lastErr error
+
+		for _, providerName := range providerNames {
+			accessType, err := getProviderAccessType(ctx, providerName)
+			if err != nil {
+				lastErr = err
+			} else if accessType != "" {
+				return accessType, nil
+			}
+		}
+
+		return "", lastErr
+	}
+
+	accessType := getOpenAIAccessType()
+	if accessType != "" {
+		return accessType, nil
+	}
+
+	accessType, err := getProxyAccessType(ctx)
+	if accessType != "" || err != nil {
+		return accessType, err
+	}
+
+	accessType = getAzureOpenAIAccessType()
+	if accessType != "" {
+		return accessType, nil
+	}
+
+	accessType = getOpenAICompatibleAccessType()
+	if accessType != "" {
+		return accessType, nil
+	}
+
+	return getAnthropicAccessType(), nil
+}
+
+// GetChatProviderName returns the lower case name of the chat provider to use,
+// which is set by SetChatProviderName() or the CHAT_API_PROVIDER environment variable.
+// An empty string indicates, that the provider should be detected by the environment.
+func GetChatProviderName() string {
+	name := chatProviderName
+	if name == "" {
+		name = os.Getenv("CHAT_API_PROVIDER")
+	}
+
+	return strings.TrimSpace(strings.ToLower(name))
+}
+
+// GetChatProviderNames returns the names of the chat providers to use in
+// the order, they should be tried, which are defined by GetChatProviderName()
+// as comma separated list.
+func GetChatProviderNames() []string {
+	return SplitChatProviderNames(GetChatProviderName())
+}
+
+// IsAnthropicConfigured checks if ANTHROPIC_API_KEY is set.
+func IsAnthropicConfigured() bool {
+	return strings.TrimSpace(os.Getenv("ANTHROPIC_API_KEY")) != ""
+}
+
+// IsAzureOpenAIConfigured checks if AZURE_OPENAI_ENDPOINT, AZURE_OPENAI_DEPLOYMENT
+// and AZURE_OPENAI_API_KEY are set.
+func IsAzureOpenAIConfigured() bool {
+	return strings.TrimSpace(os.Getenv("AZURE_OPENAI_ENDPOINT")) != "" &&
+		strings.TrimSpace(os.Getenv("AZURE_OPENAI_DEPLOYMENT")) != "" &&
+		strings.TrimSpace(os.Getenv("AZURE_OPENAI_API_KEY")) != ""
+}
+
+// IsOpenAICompatibleConfigured checks if OPENAI_COMPATIBLE_BASE_URL is set.
+func IsOpenAICompatibleConfigured() bool {
+	return strings.TrimSpace(os.Getenv("OPENAI_COMPATIBLE_BASE_URL")) != ""
+}
+
+// SetChatProviderName sets the name of the chat provider to use, which has
+// a higher priority than the CHAT_API_PROVIDER environment variable.
+func SetChatProviderName(name string) {
+	chatProviderName = strings.TrimSpace(name)
+}
+
+// SplitChatProviderNames splits a comma separated list of provider names
+// and returns the non-empty items in lower case.
+func SplitChatProviderNames(names string) []string {
+	var providerNames []string
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(strings.ToLower(name))
+		if name != "" {
+			providerNames = append(providerNames, name)
+		}
+	}
+
+	return providerNames
+}
+
+func getAnthropicAccessType() string {
+	if IsAnthropicConfigured() {
+		return "anthropic_key"
+	}
+
+	return ""
+}
+
+func getAzureOpenAIAccessType() string {
+	if IsAzureOpenAIConfigured() {
+		return "azure_openai_key"
+	}
+
+	return ""
+}
+
+func getOpenAIAccessType() string {
 	openaiApiKey := strings.TrimSpace(os.Getenv("OPENAI_API_KEY"))
 	if openaiApiKey != "" {
-		return "openai_key", nil
+		return "openai_key"
+	}
+
+	return ""
+}
+
+func getOpenAICompatibleAccessType() string {
+	if IsOpenAICompatibleConfigured() {
+		return "openai_compatible"
 	}
 
+	return ""
+}
+
+func getProviderAccessType(ctx context.Context, providerName string) (string, error) {
+	switch providerName {
+	case "anthropic":
+		return getAnthropicAccessType(), nil
+	case "azure":
+		return getAzureOpenAIAccessType(), nil
+	case "openai":
+		return getOpenAIAccessType(), nil
+	case "openai-compatible":
+		return getOpenAICompatibleAccessType(), nil
+	case "proxy":
+		return getProxyAccessType(ctx)
+	default:
+		return providerName, nil // custom provider
+	}
+}
+
+func getProxyAccessType(ctx context.Context) (string, error) {
 	clientId := strings.TrimSpace(os.Getenv("CHAT_API_CLIENT_ID"))
 	if clientId != "" {
 		apiUrl := strings.TrimSpace(os.Getenv("CHAT_API_URL"))
@@ -42,7 +193,13 @@ func GetApiAccessType() (string, error) {
 				return "proxy_api_key", nil
 			}
 
-			_, err := GetAccessToken()
+			if IsOAuth2DeviceFlow() {
+				// the device flow requires the user to sign in, so it is
+				// started by the first API call and not for the access type
+				return "proxy_oauth2", nil
+			}
+
+			_, err := GetAccessToken(ctx)
 			if err != nil {
 				return "", err
 			}
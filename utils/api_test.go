@@ -0,0 +1,59 @@
+// This file is part of the e.GPT distribution.
+// Copyright (c) Next.e.GO Mobile SE, Aachen, Germany (https://e-go-mobile.com/)
+//
+// e-gpt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, version 3.
+//
+// e-gpt is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitChatProviderNames(t *testing.T) {
+	tests := map[string][]string{
+		"":                              nil,
+		" , ,":                          nil,
+		"openai":                        {"openai"},
+		"Proxy, OpenAI ,,anthropic":     {"proxy", "openai", "anthropic"},
+		" azure , openai-compatible , ": {"azure", "openai-compatible"},
+	}
+
+	for names, expected := range tests {
+		providerNames := SplitChatProviderNames(names)
+		if !reflect.DeepEqual(providerNames, expected) {
+			t.Errorf("SplitChatProviderNames(%q) = %#v; expected %#v", names, providerNames, expected)
+		}
+	}
+}
+
+func TestGetChatProviderNames(t *testing.T) {
+	t.Setenv("CHAT_API_PROVIDER", "Anthropic, OpenAI")
+
+	previousName := chatProviderName
+	t.Cleanup(func() {
+		chatProviderName = previousName
+	})
+
+	chatProviderName = ""
+	providerNames := GetChatProviderNames()
+	if !reflect.DeepEqual(providerNames, []string{"anthropic", "openai"}) {
+		t.Errorf("unexpected names from CHAT_API_PROVIDER: %#v", providerNames)
+	}
+
+	SetChatProviderName("azure,openai")
+	providerNames = GetChatProviderNames()
+	if !reflect.DeepEqual(providerNames, []string{"azure", "openai"}) {
+		t.Errorf("unexpected names from SetChatProviderName(): %#v", providerNames)
+	}
+}
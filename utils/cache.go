@@ -0,0 +1,210 @@
+// This file is part of the e.GPT distribution.
+// Copyright (c) Next.e.GO Mobile SE, Aachen, Germany (https://e-go-mobile.com/)
+//
+// e-gpt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, version 3.
+//
+// e-gpt is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultResponseCacheMaxSize = 50 * 1024 * 1024
+const defaultResponseCacheTTL = 24 * time.Hour
+
+var responseCacheMutex sync.Mutex
+
+// GetResponseCacheDirPath returns the path of the directory of the response cache.
+func GetResponseCacheDirPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return path.Join(homeDir, ".egpt/cache"), nil
+}
+
+// GetResponseCacheMaxSize returns the maximum size of the response cache in bytes,
+// which is defined by CHAT_CACHE_MAX_SIZE in megabytes.
+func GetResponseCacheMaxSize() int64 {
+	str := strings.TrimSpace(os.Getenv("CHAT_CACHE_MAX_SIZE"))
+	if str != "" {
+		val, err := strconv.ParseFloat(str, 64)
+		if err == nil && val > 0 {
+			return int64(val * 1024 * 1024)
+		}
+	}
+
+	return defaultResponseCacheMaxSize
+}
+
+// GetResponseCacheTTL returns the time, entries of the response cache are valid,
+// which is defined by CHAT_CACHE_TTL, either as number of seconds or as
+// duration string like `12h`.
+func GetResponseCacheTTL() time.Duration {
+	str := strings.TrimSpace(os.Getenv("CHAT_CACHE_TTL"))
+	if str != "" {
+		seconds, err := strconv.ParseFloat(str, 64)
+		if err == nil && seconds > 0 {
+			return time.Duration(seconds * float64(time.Second))
+		}
+
+		duration, err := time.ParseDuration(str)
+		if err == nil && duration > 0 {
+			return duration
+		}
+	}
+
+	return defaultResponseCacheTTL
+}
+
+// IsResponseCacheEnabled returns `true`, if CHAT_CACHE is set to a truthy value.
+func IsResponseCacheEnabled() bool {
+	return IsTruthy(os.Getenv("CHAT_CACHE"))
+}
+
+// ReadResponseCache returns the data of the entry `key`, if it exists
+// and has not expired.
+func ReadResponseCache(key string) ([]byte, bool) {
+	filePath, err := getResponseCacheFilePath(key)
+	if err != nil {
+		return nil, false
+	}
+
+	responseCacheMutex.Lock()
+	defer responseCacheMutex.Unlock()
+
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return nil, false
+	}
+
+	if time.Since(info.ModTime()) > GetResponseCacheTTL() {
+		os.Remove(filePath) // expired
+
+		return nil, false
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, false
+	}
+
+	return data, true
+}
+
+// WriteResponseCache stores `data` as entry `key` and removes expired and
+// oldest entries, if the cache exceeds GetResponseCacheMaxSize().
+func WriteResponseCache(key string, data []byte) error {
+	filePath, err := getResponseCacheFilePath(key)
+	if err != nil {
+		return err
+	}
+
+	responseCacheMutex.Lock()
+	defer responseCacheMutex.Unlock()
+
+	_, err = EnsureDir(filepath.Dir(filePath))
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(filePath, data, 0600)
+	if err != nil {
+		return err
+	}
+
+	return pruneResponseCache()
+}
+
+func getResponseCacheFilePath(key string) (string, error) {
+	dirPath, err := GetResponseCacheDirPath()
+	if err != nil {
+		return "", err
+	}
+
+	// use sub directories, so that directories do not become too large
+	return path.Join(dirPath, key[:2], key+".json"), nil
+}
+
+// pruneResponseCache removes expired entries and then the oldest ones,
+// until the cache does not exceed its maximum size
+func pruneResponseCache() error {
+	dirPath, err := GetResponseCacheDirPath()
+	if err != nil {
+		return err
+	}
+
+	type cacheFile struct {
+		modTime time.Time
+		path    string
+		size    int64
+	}
+
+	var files []cacheFile
+	var totalSize int64
+
+	ttl := GetResponseCacheTTL()
+
+	err = filepath.Walk(dirPath, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return nil
+		}
+
+		if time.Since(info.ModTime()) > ttl {
+			os.Remove(filePath)
+			return nil
+		}
+
+		files = append(files, cacheFile{
+			modTime: info.ModTime(),
+			path:    filePath,
+			size:    info.Size(),
+		})
+		totalSize += info.Size()
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	maxSize := GetResponseCacheMaxSize()
+	if totalSize <= maxSize {
+		return nil
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
+
+	for _, file := range files {
+		if totalSize <= maxSize {
+			break
+		}
+
+		err := os.Remove(file.path)
+		if err == nil {
+			totalSize -= file.size
+		}
+	}
+
+	return nil
+}
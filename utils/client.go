@@ -0,0 +1,235 @@
+// This file is part of the e.GPT distribution.
+// Copyright (c) Next.e.GO Mobile SE, Aachen, Germany (https://e-go-mobile.com/)
+//
+// e-gpt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, version 3.
+//
+// e-gpt is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultApiMaxAttempts = 3
+const defaultApiTimeout = 5 * time.Minute
+const maxRetryDelay = 30 * time.Second
+
+var httpClient = &http.Client{}
+
+// ResponseError is the error of an API call, which has been answered
+// with an unexpected HTTP status code.
+type ResponseError struct {
+	Message    string // The error message, including the response body
+	StatusCode int    // The HTTP status code
+}
+
+// GetApiMaxAttempts returns the maximum number of attempts for a single API call,
+// which is defined by the CHAT_API_MAX_ATTEMPTS environment variable.
+func GetApiMaxAttempts() int {
+	str := strings.TrimSpace(os.Getenv("CHAT_API_MAX_ATTEMPTS"))
+	if str != "" {
+		val, err := strconv.Atoi(str)
+		if err == nil && val > 0 {
+			return val
+		}
+	}
+
+	return defaultApiMaxAttempts // default
+}
+
+// GetApiTimeout returns the maximum duration of a single API call, which is
+// defined by the CHAT_API_TIMEOUT environment variable, either as number of
+// seconds or as duration string like `90s`. A value of 0 means no timeout.
+func GetApiTimeout() time.Duration {
+	str := strings.TrimSpace(os.Getenv("CHAT_API_TIMEOUT"))
+	if str == "" {
+		return defaultApiTimeout
+	}
+
+	seconds, err := strconv.ParseFloat(str, 64)
+	if err == nil {
+		if seconds < 0 {
+			return 0
+		}
+
+		return time.Duration(seconds * float64(time.Second))
+	}
+
+	duration, err := time.ParseDuration(str)
+	if err == nil {
+		if duration < 0 {
+			return 0
+		}
+
+		return duration
+	}
+
+	return defaultApiTimeout // default / fallback
+}
+
+// Error implements error.Error().
+func (e *ResponseError) Error() string {
+	return e.Message
+}
+
+// GetHttpClient returns the HTTP client, which is shared by all API calls.
+func GetHttpClient() *http.Client {
+	return httpClient
+}
+
+// NewResponseError creates a new ResponseError from `response`, with
+// `message`, the status code and the body of `response` as error message.
+func NewResponseError(message string, response *http.Response) *ResponseError {
+	return &ResponseError{
+		Message:    GetResponseErrorMessage(message, response),
+		StatusCode: response.StatusCode,
+	}
+}
+
+// WithApiTimeout returns a copy of `ctx`, which is canceled after GetApiTimeout().
+func WithApiTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	timeout := GetApiTimeout()
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, timeout)
+}
+
+// SendHttpRequest sends a request, created by `createRequest`, with the shared
+// HTTP client. If the API answers with 429, 500, 502 or 503 or a transient
+// network error occurs, the request is repeated with an exponential backoff or
+// the delay of the `Retry-After` header, until GetApiMaxAttempts() is reached.
+// The response or error of the last attempt is returned.
+func SendHttpRequest(ctx context.Context, createRequest func(ctx context.Context) (*http.Request, error)) (*http.Response, error) {
+	maxAttempts := GetApiMaxAttempts()
+
+	for attempt := 1; ; attempt++ {
+		request, err := createRequest(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		response, err := GetHttpClient().Do(request)
+		if err != nil {
+			if attempt >= maxAttempts || ctx.Err() != nil || !isRetryableError(err) {
+				return nil, err
+			}
+		} else if attempt >= maxAttempts || !isRetryableStatusCode(response.StatusCode) {
+			return response, nil
+		}
+
+		delay := getBackoffDelay(attempt)
+		if response != nil {
+			retryAfterDelay, ok := getRetryAfterDelay(response.Header.Get("Retry-After"))
+			if ok {
+				delay = retryAfterDelay
+			}
+
+			response.Body.Close()
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// getBackoffDelay returns the time to wait before the next attempt,
+// after `attempt` has failed
+func getBackoffDelay(attempt int) time.Duration {
+	// 1s, 2s, 4s ... with a jitter of +/- 50%
+	delay := time.Duration(math.Pow(2, float64(attempt-1)) * float64(time.Second))
+	delay = time.Duration(float64(delay) * (0.5 + rand.Float64()))
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+
+	return delay
+}
+
+// getRetryAfterDelay returns the delay of the value `retryAfter` of a
+// `Retry-After` header, as seconds or HTTP date, which is at most
+// maxRetryDelay, and `false`, if it is invalid
+func getRetryAfterDelay(retryAfter string) (time.Duration, bool) {
+	retryAfter = strings.TrimSpace(retryAfter)
+	if retryAfter == "" {
+		return 0, false
+	}
+
+	var delay time.Duration
+
+	seconds, err := strconv.Atoi(retryAfter)
+	if err == nil {
+		delay = time.Duration(seconds) * time.Second
+	} else {
+		date, err := http.ParseTime(retryAfter)
+		if err != nil {
+			return 0, false
+		}
+
+		delay = time.Until(date)
+	}
+
+	if delay < 0 {
+		delay = 0
+	}
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+
+	return delay, true
+}
+
+// isRetryableError checks if `err` of an HTTP request is a transient network
+// error, like a timeout, a reset or a refused connection
+func isRetryableError(err error) bool {
+	// url.Error implements net.Error itself
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err
+	}
+
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true // connection has been closed by the server
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTimeout || dnsErr.IsTemporary
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+func isRetryableStatusCode(statusCode int) bool {
+	return statusCode == 429 ||
+		statusCode == 500 ||
+		statusCode == 502 ||
+		statusCode == 503
+}
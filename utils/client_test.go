@@ -0,0 +1,118 @@
+// This file is part of the e.GPT distribution.
+// Copyright (c) Next.e.GO Mobile SE, Aachen, Germany (https://e-go-mobile.com/)
+//
+// e-gpt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, version 3.
+//
+// e-gpt is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetRetryAfterDelay(t *testing.T) {
+	tests := []struct {
+		retryAfter string
+		delay      time.Duration
+		ok         bool
+	}{
+		{"", 0, false},
+		{"abc", 0, false},
+		{"0", 0, true},
+		{"-5", 0, true},
+		{" 3 ", 3 * time.Second, true},
+		{"3600", maxRetryDelay, true},
+		{time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), 0, true},
+		{time.Now().Add(time.Hour).UTC().Format(http.TimeFormat), maxRetryDelay, true},
+	}
+
+	for _, test := range tests {
+		delay, ok := getRetryAfterDelay(test.retryAfter)
+		if delay != test.delay || ok != test.ok {
+			t.Errorf("getRetryAfterDelay(%q) = %v, %v; expected %v, %v", test.retryAfter, delay, ok, test.delay, test.ok)
+		}
+	}
+}
+
+func TestGetBackoffDelay(t *testing.T) {
+	for attempt := 1; attempt <= 10; attempt++ {
+		delay := getBackoffDelay(attempt)
+		if delay <= 0 || delay > maxRetryDelay {
+			t.Errorf("getBackoffDelay(%v) = %v", attempt, delay)
+		}
+	}
+}
+
+func TestIsRetryableError(t *testing.T) {
+	tests := []struct {
+		err         error
+		isRetryable bool
+	}{
+		{&url.Error{Op: "Post", URL: "http://localhost", Err: io.EOF}, true},
+		{&url.Error{Op: "Post", URL: "http://localhost", Err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}}, true},
+		{&url.Error{Op: "Post", URL: "http://localhost", Err: &net.DNSError{Err: "no such host", IsNotFound: true}}, false},
+		{&url.Error{Op: "Post", URL: "http://localhost", Err: &net.DNSError{Err: "timeout", IsTimeout: true}}, true},
+		{&url.Error{Op: "Post", URL: "foo://localhost", Err: errors.New("unsupported protocol scheme")}, false},
+		{errors.New("other"), false},
+	}
+
+	for _, test := range tests {
+		isRetryable := isRetryableError(test.err)
+		if isRetryable != test.isRetryable {
+			t.Errorf("isRetryableError(%v) = %v; expected %v", test.err, isRetryable, test.isRetryable)
+		}
+	}
+}
+
+func TestSendHttpRequestRetriesNetworkErrors(t *testing.T) {
+	t.Setenv("CHAT_API_MAX_ATTEMPTS", "2")
+
+	var requestCount int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requestCount, 1) == 1 {
+			// close the connection without response
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err == nil {
+				conn.Close()
+			}
+			return
+		}
+
+		fmt.Fprint(w, "ok")
+	}))
+	defer server.Close()
+
+	response, err := SendHttpRequest(context.Background(), func(ctx context.Context) (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, "POST", server.URL, nil)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != 200 {
+		t.Fatalf("unexpected status code %v", response.StatusCode)
+	}
+	if requestCount != 2 {
+		t.Fatalf("expected 2 requests, got %v", requestCount)
+	}
+}
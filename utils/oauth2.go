@@ -0,0 +1,249 @@
+// This file is part of the e.GPT distribution.
+// Copyright (c) Next.e.GO Mobile SE, Aachen, Germany (https://e-go-mobile.com/)
+//
+// e-gpt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, version 3.
+//
+// e-gpt is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const deviceCodeGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+
+// oauth2DeviceIntervalUnit is the unit of the polling interval of the device flow
+var oauth2DeviceIntervalUnit = time.Second
+
+// oauth2DeviceAuthorizationResponse is the response of a device authorization
+// endpoint, s. RFC 8628
+type oauth2DeviceAuthorizationResponse struct {
+	DeviceCode              string `json:"device_code"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+	UserCode                string `json:"user_code"`
+	VerificationUri         string `json:"verification_uri"`
+	VerificationUriComplete string `json:"verification_uri_complete"`
+}
+
+// oauth2ResponseError is returned by sendOAuth2Request(), if an OAuth 2
+// endpoint does not answer with 200
+type oauth2ResponseError struct {
+	Code     string         // the value of the `error` property of the response body, if available
+	Response *ResponseError // the status code and the complete error message
+}
+
+func (e *oauth2ResponseError) Error() string {
+	return e.Response.Error()
+}
+
+func (e *oauth2ResponseError) Unwrap() error {
+	return e.Response
+}
+
+// IsOAuth2DeviceFlow returns `true`, if OAUTH2_GRANT_TYPE is set to `device_code`,
+// which means that access tokens require a user interaction.
+func IsOAuth2DeviceFlow() bool {
+	return getOAuth2GrantType() == "device_code"
+}
+
+func addOAuth2ScopeAndAudience(body url.Values) {
+	scope := strings.TrimSpace(os.Getenv("OAUTH2_SCOPE"))
+	if scope != "" {
+		body.Set("scope", scope)
+	}
+
+	audience := strings.TrimSpace(os.Getenv("OAUTH2_AUDIENCE"))
+	if audience != "" {
+		body.Set("audience", audience)
+	}
+}
+
+// getOAuth2ClientAuth returns the value of OAUTH2_CLIENT_AUTH, which is
+// `body` (default) or `basic`
+func getOAuth2ClientAuth() string {
+	clientAuth := strings.TrimSpace(strings.ToLower(os.Getenv("OAUTH2_CLIENT_AUTH")))
+	if clientAuth == "basic" {
+		return clientAuth
+	}
+
+	return "body"
+}
+
+// getOAuth2GrantType returns the value of OAUTH2_GRANT_TYPE, which is
+// `client_credentials` (default) or `device_code`
+func getOAuth2GrantType() string {
+	grantType := strings.TrimSpace(strings.ToLower(os.Getenv("OAUTH2_GRANT_TYPE")))
+	if grantType == "device_code" || grantType == deviceCodeGrantType {
+		return "device_code"
+	}
+
+	return "client_credentials"
+}
+
+// refreshAccessToken requests a new access token with `refreshToken`
+func refreshAccessToken(ctx context.Context, refreshToken string) (OAuth2TokenResponse, error) {
+	body := url.Values{}
+	body.Set("grant_type", "refresh_token")
+	body.Set("refresh_token", refreshToken)
+
+	return requestTokenEndpoint(ctx, body)
+}
+
+// requestAccessToken requests a new access token with the grant type of OAUTH2_GRANT_TYPE
+func requestAccessToken(ctx context.Context) (OAuth2TokenResponse, error) {
+	if IsOAuth2DeviceFlow() {
+		return requestDeviceAccessToken(ctx)
+	}
+
+	body := url.Values{}
+	body.Set("grant_type", "client_credentials")
+	addOAuth2ScopeAndAudience(body)
+
+	return requestTokenEndpoint(ctx, body)
+}
+
+// requestDeviceAccessToken runs the device authorization flow of RFC 8628:
+// it asks the user to open a URL and polls the token endpoint, until the user
+// has signed in
+func requestDeviceAccessToken(ctx context.Context) (OAuth2TokenResponse, error) {
+	var tokenResponse OAuth2TokenResponse
+
+	deviceAuthorizationUrl := strings.TrimSpace(os.Getenv("OAUTH2_DEVICE_AUTHORIZATION_URL"))
+	if deviceAuthorizationUrl == "" {
+		return tokenResponse, errors.New("no OAuth 2 device authorization URL defined")
+	}
+
+	body := url.Values{}
+	addOAuth2ScopeAndAudience(body)
+
+	var authorization oauth2DeviceAuthorizationResponse
+	err := sendOAuth2Request(ctx, deviceAuthorizationUrl, body, "The device authorization failed:", &authorization)
+	if err != nil {
+		return tokenResponse, err
+	}
+
+	verificationUri := authorization.VerificationUri
+	if authorization.VerificationUriComplete != "" {
+		verificationUri = authorization.VerificationUriComplete
+	}
+
+	// use STDERR, so the output of commands is not touched
+	os.Stderr.WriteString(
+		fmt.Sprintf("To sign in, open %v and enter the code %v\n", verificationUri, authorization.UserCode),
+	)
+
+	interval := 5 * oauth2DeviceIntervalUnit
+	if authorization.Interval > 0 {
+		interval = time.Duration(authorization.Interval) * oauth2DeviceIntervalUnit
+	}
+
+	expiresIn := 10 * time.Minute
+	if authorization.ExpiresIn > 0 {
+		expiresIn = time.Duration(authorization.ExpiresIn) * time.Second
+	}
+
+	deadline := time.Now().Add(expiresIn)
+
+	for {
+		if time.Now().After(deadline) {
+			return tokenResponse, errors.New("the device code has expired")
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return tokenResponse, ctx.Err()
+		case <-timer.C:
+		}
+
+		body := url.Values{}
+		body.Set("grant_type", deviceCodeGrantType)
+		body.Set("device_code", authorization.DeviceCode)
+
+		tokenResponse, err = requestTokenEndpoint(ctx, body)
+		if err == nil {
+			return tokenResponse, nil
+		}
+
+		var responseError *oauth2ResponseError
+		if !errors.As(err, &responseError) {
+			return tokenResponse, err
+		}
+
+		switch responseError.Code {
+		case "authorization_pending":
+			continue
+		case "slow_down":
+			interval += 5 * oauth2DeviceIntervalUnit
+			continue
+		}
+
+		return tokenResponse, err
+	}
+}
+
+// requestTokenEndpoint sends `body` to the endpoint of OAUTH2_GET_TOKEN_URL
+func requestTokenEndpoint(ctx context.Context, body url.Values) (OAuth2TokenResponse, error) {
+	var tokenResponse OAuth2TokenResponse
+
+	getApiTokenUrl := strings.TrimSpace(os.Getenv("OAUTH2_GET_TOKEN_URL"))
+
+	err := sendOAuth2Request(ctx, getApiTokenUrl, body, "The credentials do not work:", &tokenResponse)
+
+	return tokenResponse, err
+}
+
+// sendOAuth2Request sends `body` as form to `requestUrl` and parses the JSON of a
+// successful response into `result`
+func sendOAuth2Request(ctx context.Context, requestUrl string, body url.Values, errorMessage string, result interface{}) error {
+	ctx, cancel := WithApiTimeout(ctx)
+	defer cancel()
+
+	response, err := getAccessTokenHttpResponse(ctx, requestUrl, body)
+	if err != nil {
+		return err
+	}
+
+	defer response.Body.Close()
+
+	bodyData, err := io.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+
+	if response.StatusCode != 200 {
+		var errorBody struct {
+			Error string `json:"error"`
+		}
+		json.Unmarshal(bodyData, &errorBody)
+
+		response.Body = io.NopCloser(bytes.NewReader(bodyData))
+
+		return &oauth2ResponseError{
+			Code:     errorBody.Error,
+			Response: NewResponseError(errorMessage, response),
+		}
+	}
+
+	return json.Unmarshal(bodyData, result) // try parse as JSON
+}
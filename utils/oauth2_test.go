@@ -0,0 +1,214 @@
+// This file is part of the e.GPT distribution.
+// Copyright (c) Next.e.GO Mobile SE, Aachen, Germany (https://e-go-mobile.com/)
+//
+// e-gpt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, version 3.
+//
+// e-gpt is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRequestDeviceAccessToken(t *testing.T) {
+	previousUnit := oauth2DeviceIntervalUnit
+	oauth2DeviceIntervalUnit = 10 * time.Millisecond
+	t.Cleanup(func() {
+		oauth2DeviceIntervalUnit = previousUnit
+	})
+
+	var pollTimes []time.Time
+	var lock sync.Mutex
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/device", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.PostForm.Get("client_id") != "client" || r.PostForm.Get("scope") != "chat" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"device_code":"device","user_code":"ABCD","verification_uri":"https://example.com/device","interval":1,"expires_in":60}`))
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.PostForm.Get("grant_type") != deviceCodeGrantType || r.PostForm.Get("device_code") != "device" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"invalid_request"}`))
+			return
+		}
+
+		lock.Lock()
+		pollTimes = append(pollTimes, time.Now())
+		poll := len(pollTimes)
+		lock.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		switch poll {
+		case 1:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"authorization_pending"}`))
+		case 2:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"slow_down"}`))
+		default:
+			w.Write([]byte(`{"access_token":"token","expires_in":3600}`))
+		}
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	t.Setenv("CHAT_API_CLIENT_ID", "client")
+	t.Setenv("CHAT_API_CLIENT_SECRET", "")
+	t.Setenv("OAUTH2_AUDIENCE", "")
+	t.Setenv("OAUTH2_DEVICE_AUTHORIZATION_URL", server.URL+"/device")
+	t.Setenv("OAUTH2_GET_TOKEN_URL", server.URL+"/token")
+	t.Setenv("OAUTH2_SCOPE", "chat")
+
+	token, err := requestDeviceAccessToken(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.AccessToken != "token" {
+		t.Fatalf("unexpected token %q", token.AccessToken)
+	}
+
+	if len(pollTimes) != 3 {
+		t.Fatalf("expected 3 polls, got %v", len(pollTimes))
+	}
+
+	// `slow_down` increases the interval by 5 units
+	slowDownInterval := pollTimes[2].Sub(pollTimes[1])
+	if slowDownInterval < 6*oauth2DeviceIntervalUnit {
+		t.Fatalf("interval after slow_down is %v", slowDownInterval)
+	}
+}
+
+func TestRequestDeviceAccessTokenFailsOnOtherErrors(t *testing.T) {
+	previousUnit := oauth2DeviceIntervalUnit
+	oauth2DeviceIntervalUnit = 10 * time.Millisecond
+	t.Cleanup(func() {
+		oauth2DeviceIntervalUnit = previousUnit
+	})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/device", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"device_code":"device","user_code":"ABCD","verification_uri":"https://example.com/device","interval":1}`))
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"access_denied"}`))
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	t.Setenv("CHAT_API_CLIENT_ID", "client")
+	t.Setenv("OAUTH2_DEVICE_AUTHORIZATION_URL", server.URL+"/device")
+	t.Setenv("OAUTH2_GET_TOKEN_URL", server.URL+"/token")
+
+	_, err := requestDeviceAccessToken(context.Background())
+
+	responseError, ok := err.(*oauth2ResponseError)
+	if !ok || responseError.Code != "access_denied" {
+		t.Fatalf("expected access_denied error, got %v", err)
+	}
+}
+
+func TestRequestAccessTokenWithClientAuth(t *testing.T) {
+	type credentials struct {
+		basicId     string
+		basicSecret string
+		bodyId      string
+		bodySecret  string
+	}
+
+	var received credentials
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+
+		received = credentials{
+			bodyId:     r.PostForm.Get("client_id"),
+			bodySecret: r.PostForm.Get("client_secret"),
+		}
+		received.basicId, received.basicSecret, _ = r.BasicAuth()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"token","expires_in":3600}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("CHAT_API_CLIENT_ID", "my client")
+	t.Setenv("CHAT_API_CLIENT_SECRET", "a:b&c")
+	t.Setenv("OAUTH2_GET_TOKEN_URL", server.URL)
+	t.Setenv("OAUTH2_GRANT_TYPE", "")
+
+	tests := map[string]credentials{
+		"":      {bodyId: "my client", bodySecret: "a:b&c"},
+		"body":  {bodyId: "my client", bodySecret: "a:b&c"},
+		"basic": {basicId: "my+client", basicSecret: "a%3Ab%26c"}, // s. RFC 6749, section 2.3.1
+	}
+
+	for clientAuth, expected := range tests {
+		t.Setenv("OAUTH2_CLIENT_AUTH", clientAuth)
+
+		token, err := requestAccessToken(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token.AccessToken != "token" {
+			t.Fatalf("unexpected token %q", token.AccessToken)
+		}
+		if received != expected {
+			t.Errorf("client auth %q sent %+v; expected %+v", clientAuth, received, expected)
+		}
+	}
+}
+
+func TestGetProxyAccessTypeDoesNotStartDeviceFlow(t *testing.T) {
+	resetAccessTokenCache(t)
+
+	requestCount := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestCount++
+
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	t.Setenv("CHAT_API_CLIENT_ID", "client")
+	t.Setenv("CHAT_API_KEY", "")
+	t.Setenv("CHAT_API_URL", "https://api.example.com/v1/chat/completions")
+	t.Setenv("OAUTH2_DEVICE_AUTHORIZATION_URL", server.URL)
+	t.Setenv("OAUTH2_GET_TOKEN_URL", server.URL)
+	t.Setenv("OAUTH2_GRANT_TYPE", "device_code")
+
+	accessType, err := getProxyAccessType(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if accessType != "proxy_oauth2" {
+		t.Fatalf("unexpected access type %q", accessType)
+	}
+	if requestCount != 0 {
+		t.Fatalf("expected no request, got %v", requestCount)
+	}
+}
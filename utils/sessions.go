@@ -0,0 +1,167 @@
+// This file is part of the e.GPT distribution.
+// Copyright (c) Next.e.GO Mobile SE, Aachen, Germany (https://e-go-mobile.com/)
+//
+// e-gpt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, version 3.
+//
+// e-gpt is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+var sessionNameRegex = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
+
+// ChatSession is a named conversation, which is stored in the sessions directory
+type ChatSession struct {
+	Conversation []string  `json:"conversation"` // alternating messages of user and assistant
+	CreatedAt    time.Time `json:"createdAt"`    // the time the session has been created
+	Name         string    `json:"name"`         // the name of the session
+	UpdatedAt    time.Time `json:"updatedAt"`    // the time of the last update
+}
+
+// DeleteChatSession deletes the session with the name `name`.
+func DeleteChatSession(name string) error {
+	filePath, err := getChatSessionFilePath(name)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(filePath)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("session %v not found", name)
+	}
+
+	return err
+}
+
+// GetSessionsDirPath returns the path of the directory, where chat sessions are stored.
+func GetSessionsDirPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return path.Join(homeDir, ".egpt/sessions"), nil
+}
+
+// ListChatSessions returns all stored sessions, sorted by their name.
+func ListChatSessions() ([]ChatSession, error) {
+	dirPath, err := GetSessionsDirPath()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dirPath)
+	if os.IsNotExist(err) {
+		return []ChatSession{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := []ChatSession{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+
+		session, err := LoadChatSession(strings.TrimSuffix(entry.Name(), ".json"))
+		if err != nil {
+			continue // ignore invalid files
+		}
+
+		sessions = append(sessions, session)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].Name < sessions[j].Name
+	})
+
+	return sessions, nil
+}
+
+// LoadChatSession loads the session with the name `name`. If it does not
+// exist, a new and empty session is returned.
+func LoadChatSession(name string) (ChatSession, error) {
+	session := ChatSession{
+		Conversation: []string{},
+		CreatedAt:    time.Now(),
+		Name:         name,
+	}
+	session.UpdatedAt = session.CreatedAt
+
+	filePath, err := getChatSessionFilePath(name)
+	if err != nil {
+		return session, err
+	}
+
+	data, err := os.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return session, nil
+	}
+	if err != nil {
+		return session, err
+	}
+
+	err = json.Unmarshal(data, &session)
+	session.Name = name
+
+	return session, err
+}
+
+// SaveChatSession stores `session` in the sessions directory.
+func SaveChatSession(session *ChatSession) error {
+	filePath, err := getChatSessionFilePath(session.Name)
+	if err != nil {
+		return err
+	}
+
+	dirPath, err := GetSessionsDirPath()
+	if err != nil {
+		return err
+	}
+
+	_, err = EnsureDir(dirPath)
+	if err != nil {
+		return err
+	}
+
+	session.UpdatedAt = time.Now()
+
+	data, err := json.MarshalIndent(session, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filePath, data, 0600)
+}
+
+func getChatSessionFilePath(name string) (string, error) {
+	if !sessionNameRegex.MatchString(name) || strings.Trim(name, ".") == "" {
+		return "", fmt.Errorf("invalid session name %v, only letters, digits, dots, dashes and underscores are allowed", name)
+	}
+
+	dirPath, err := GetSessionsDirPath()
+	if err != nil {
+		return "", err
+	}
+
+	return path.Join(dirPath, name+".json"), nil
+}
@@ -0,0 +1,277 @@
+// This file is part of the e.GPT distribution.
+// Copyright (c) Next.e.GO Mobile SE, Aachen, Germany (https://e-go-mobile.com/)
+//
+// e-gpt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, version 3.
+//
+// e-gpt is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// the time before the real expiration, a token is not used anymore
+const accessTokenExpirationMargin = 60 * time.Second
+
+// the lifetime of tokens, whose response does not contain `expires_in`
+const defaultAccessTokenLifetime = 5 * time.Minute
+
+// cachedAccessToken is an access token in the cache
+type cachedAccessToken struct {
+	ExpiresAt time.Time           `json:"expiresAt"` // the time the token expires
+	Key       string              `json:"key"`       // the hash of the credentials, the token belongs to
+	Token     OAuth2TokenResponse `json:"token"`     // the token response
+}
+
+// pendingAccessToken is a request of a new access token, which is in progress
+type pendingAccessToken struct {
+	done  chan struct{}       // closed, when the request has finished
+	err   error               // the error of the request
+	token OAuth2TokenResponse // the token response
+}
+
+var accessTokenCache *cachedAccessToken
+var accessTokenCacheMutex sync.Mutex
+var pendingAccessTokenRequest *pendingAccessToken
+
+// ClearAccessTokenCache removes the current access token from memory and disk.
+func ClearAccessTokenCache() error {
+	accessTokenCacheMutex.Lock()
+	defer accessTokenCacheMutex.Unlock()
+
+	accessTokenCache = nil
+
+	filePath, err := GetAccessTokenCacheFilePath()
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(filePath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// GetAccessTokenCacheFilePath returns the path of the file, where access tokens
+// are cached, if OAUTH2_TOKEN_CACHE is set to `disk`.
+func GetAccessTokenCacheFilePath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return path.Join(homeDir, ".egpt/.oauth2_token.json"), nil
+}
+
+// getAccessTokenCacheKey returns a hash of the current credentials, so a cached
+// token is not used for other credentials.
+func getAccessTokenCacheKey() string {
+	hash := sha256.New()
+	for _, name := range []string{
+		"OAUTH2_GET_TOKEN_URL", "OAUTH2_GRANT_TYPE", "OAUTH2_SCOPE", "OAUTH2_AUDIENCE",
+		"CHAT_API_CLIENT_ID", "CHAT_API_CLIENT_SECRET",
+	} {
+		hash.Write([]byte(strings.TrimSpace(os.Getenv(name))))
+		hash.Write([]byte{0})
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// getAccessTokenCacheMode returns the value of OAUTH2_TOKEN_CACHE,
+// which is `memory` (default), `disk` or `none`.
+func getAccessTokenCacheMode() string {
+	mode := strings.TrimSpace(strings.ToLower(os.Getenv("OAUTH2_TOKEN_CACHE")))
+	if mode == "disk" || mode == "none" {
+		return mode
+	}
+
+	return "memory"
+}
+
+// getCachedAccessToken returns a valid token from the cache or requests a new
+// one with `refreshToken` or `requestToken` and stores it.
+func getCachedAccessToken(
+	ctx context.Context,
+	requestToken func(ctx context.Context) (OAuth2TokenResponse, error),
+	refreshToken func(ctx context.Context, refreshToken string) (OAuth2TokenResponse, error),
+) (OAuth2TokenResponse, error) {
+	mode := getAccessTokenCacheMode()
+	if mode == "none" {
+		return requestToken(ctx)
+	}
+
+	key := getAccessTokenCacheKey()
+
+	accessTokenCacheMutex.Lock()
+
+	if accessTokenCache == nil && mode == "disk" {
+		accessTokenCache = readAccessTokenCacheFile()
+	}
+
+	if accessTokenCache != nil && accessTokenCache.isValid(key) {
+		defer accessTokenCacheMutex.Unlock()
+
+		return accessTokenCache.Token, nil
+	}
+
+	// concurrent calls, like from the UI server, wait for the running request
+	// instead of requesting more than one new token; the lock is not held
+	// meanwhile, because the device flow can take minutes
+	pending := pendingAccessTokenRequest
+	if pending != nil {
+		accessTokenCacheMutex.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return OAuth2TokenResponse{}, ctx.Err()
+		case <-pending.done:
+			if errors.Is(pending.err, context.Canceled) && ctx.Err() == nil {
+				// the request has been canceled by the context of the caller,
+				// which started it, like a closed connection of the UI server
+				return getCachedAccessToken(ctx, requestToken, refreshToken)
+			}
+
+			return pending.token, pending.err
+		}
+	}
+
+	pending = &pendingAccessToken{
+		done: make(chan struct{}),
+	}
+	pendingAccessTokenRequest = pending
+
+	var currentRefreshToken string
+	if accessTokenCache != nil && accessTokenCache.Key == key {
+		currentRefreshToken = accessTokenCache.Token.RefreshToken
+	}
+
+	accessTokenCacheMutex.Unlock()
+
+	pending.token, pending.err = requestNewAccessToken(ctx, currentRefreshToken, requestToken, refreshToken)
+
+	accessTokenCacheMutex.Lock()
+	defer accessTokenCacheMutex.Unlock()
+
+	if pending.err == nil {
+		expiresIn := defaultAccessTokenLifetime
+		if pending.token.ExpiresIn > 0 {
+			expiresIn = time.Duration(pending.token.ExpiresIn) * time.Second
+		}
+
+		accessTokenCache = &cachedAccessToken{
+			ExpiresAt: time.Now().Add(expiresIn),
+			Key:       key,
+			Token:     pending.token,
+		}
+
+		if mode == "disk" {
+			writeAccessTokenCacheFile(accessTokenCache)
+		}
+	}
+
+	pendingAccessTokenRequest = nil
+	close(pending.done)
+
+	return pending.token, pending.err
+}
+
+// isValid checks if `token` belongs to `key` and does not expire soon
+func (token *cachedAccessToken) isValid(key string) bool {
+	return token.Key == key &&
+		token.Token.AccessToken != "" &&
+		time.Now().Add(accessTokenExpirationMargin).Before(token.ExpiresAt)
+}
+
+// readAccessTokenCacheFile reads the cached token from disk, if available
+func readAccessTokenCacheFile() *cachedAccessToken {
+	filePath, err := GetAccessTokenCacheFilePath()
+	if err != nil {
+		return nil
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil
+	}
+
+	var token cachedAccessToken
+	err = json.Unmarshal(data, &token)
+	if err != nil {
+		return nil
+	}
+
+	return &token
+}
+
+// requestNewAccessToken requests a new token with `currentRefreshToken`,
+// if defined, or `requestToken`
+func requestNewAccessToken(
+	ctx context.Context,
+	currentRefreshToken string,
+	requestToken func(ctx context.Context) (OAuth2TokenResponse, error),
+	refreshToken func(ctx context.Context, refreshToken string) (OAuth2TokenResponse, error),
+) (OAuth2TokenResponse, error) {
+	if currentRefreshToken != "" {
+		tokenResponse, err := refreshToken(ctx, currentRefreshToken)
+		if err == nil && tokenResponse.AccessToken != "" {
+			if tokenResponse.RefreshToken == "" {
+				// keep current refresh token, if no new one has been issued
+				tokenResponse.RefreshToken = currentRefreshToken
+			}
+
+			return tokenResponse, nil
+		}
+	}
+
+	return requestToken(ctx)
+}
+
+// writeAccessTokenCacheFile writes `token` to disk, which is only readable by
+// the current user; errors are ignored, because the cache is optional
+func writeAccessTokenCacheFile(token *cachedAccessToken) {
+	filePath, err := GetAccessTokenCacheFilePath()
+	if err != nil {
+		return
+	}
+
+	_, err = EnsureDir(filepath.Dir(filePath))
+	if err != nil {
+		return
+	}
+
+	data, err := json.Marshal(token)
+	if err != nil {
+		return
+	}
+
+	file, err := os.OpenFile(filePath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	file.Chmod(0600) // in case the file already existed
+	file.Write(data)
+}
@@ -0,0 +1,231 @@
+// This file is part of the e.GPT distribution.
+// Copyright (c) Next.e.GO Mobile SE, Aachen, Germany (https://e-go-mobile.com/)
+//
+// e-gpt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, version 3.
+//
+// e-gpt is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func resetAccessTokenCache(t *testing.T) {
+	t.Setenv("OAUTH2_TOKEN_CACHE", "memory")
+
+	accessTokenCache = nil
+	pendingAccessTokenRequest = nil
+	t.Cleanup(func() {
+		accessTokenCache = nil
+		pendingAccessTokenRequest = nil
+	})
+}
+
+func TestGetCachedAccessTokenCachesTokensWithoutExpiresIn(t *testing.T) {
+	resetAccessTokenCache(t)
+
+	requestCount := 0
+	requestToken := func(ctx context.Context) (OAuth2TokenResponse, error) {
+		requestCount++
+		return OAuth2TokenResponse{AccessToken: "token"}, nil
+	}
+
+	for i := 0; i < 2; i++ {
+		token, err := getCachedAccessToken(context.Background(), requestToken, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token.AccessToken != "token" {
+			t.Fatalf("unexpected token %q", token.AccessToken)
+		}
+	}
+
+	if requestCount != 1 {
+		t.Fatalf("expected 1 token request, got %v", requestCount)
+	}
+
+	lifetime := time.Until(accessTokenCache.ExpiresAt)
+	if lifetime <= 0 || lifetime > defaultAccessTokenLifetime {
+		t.Fatalf("unexpected lifetime %v", lifetime)
+	}
+}
+
+func TestGetCachedAccessTokenDoesNotLockWhileRequesting(t *testing.T) {
+	resetAccessTokenCache(t)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	var requestCount int
+	var requestCountLock sync.Mutex
+	requestToken := func(ctx context.Context) (OAuth2TokenResponse, error) {
+		requestCountLock.Lock()
+		requestCount++
+		requestCountLock.Unlock()
+
+		close(started)
+		<-release
+
+		return OAuth2TokenResponse{AccessToken: "token", ExpiresIn: 3600}, nil
+	}
+
+	var wg sync.WaitGroup
+	tokens := make([]string, 2)
+	for i := range tokens {
+		if i > 0 {
+			<-started
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			token, err := getCachedAccessToken(context.Background(), requestToken, nil)
+			if err != nil {
+				t.Error(err)
+			}
+			tokens[i] = token.AccessToken
+		}(i)
+	}
+
+	// the first call is requesting the token now
+	isUnlocked := make(chan struct{})
+	go func() {
+		accessTokenCacheMutex.Lock()
+		accessTokenCacheMutex.Unlock()
+
+		close(isUnlocked)
+	}()
+
+	select {
+	case <-isUnlocked:
+	case <-time.After(5 * time.Second):
+		t.Fatal("cache is locked during the token request")
+	}
+
+	close(release)
+	wg.Wait()
+
+	if requestCount != 1 {
+		t.Fatalf("expected 1 token request, got %v", requestCount)
+	}
+	for _, token := range tokens {
+		if token != "token" {
+			t.Fatalf("unexpected token %q", token)
+		}
+	}
+}
+
+func TestGetCachedAccessTokenRetriesAfterCanceledRequest(t *testing.T) {
+	resetAccessTokenCache(t)
+
+	started := make(chan struct{})
+
+	var requestCount int
+	var requestCountLock sync.Mutex
+	requestToken := func(ctx context.Context) (OAuth2TokenResponse, error) {
+		requestCountLock.Lock()
+		requestCount++
+		isFirst := requestCount == 1
+		requestCountLock.Unlock()
+
+		if isFirst {
+			close(started)
+
+			<-ctx.Done()
+			return OAuth2TokenResponse{}, ctx.Err()
+		}
+
+		return OAuth2TokenResponse{AccessToken: "token", ExpiresIn: 3600}, nil
+	}
+
+	firstCtx, cancelFirst := context.WithCancel(context.Background())
+	defer cancelFirst()
+
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := getCachedAccessToken(firstCtx, requestToken, nil)
+		firstErr <- err
+	}()
+
+	<-started
+
+	type result struct {
+		err   error
+		token OAuth2TokenResponse
+	}
+	waiterResult := make(chan result, 1)
+	go func() {
+		token, err := getCachedAccessToken(context.Background(), requestToken, nil)
+		waiterResult <- result{err: err, token: token}
+	}()
+
+	// give the second caller time to wait for the running request
+	time.Sleep(100 * time.Millisecond)
+	cancelFirst()
+
+	err := <-firstErr
+	if err != context.Canceled {
+		t.Fatalf("expected canceled first request, got %v", err)
+	}
+
+	select {
+	case res := <-waiterResult:
+		if res.err != nil {
+			t.Fatal(res.err)
+		}
+		if res.token.AccessToken != "token" {
+			t.Fatalf("unexpected token %q", res.token.AccessToken)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("waiting caller did not get a token")
+	}
+
+	if requestCount != 2 {
+		t.Fatalf("expected 2 token requests, got %v", requestCount)
+	}
+}
+
+func TestGetCachedAccessTokenUsesRefreshToken(t *testing.T) {
+	resetAccessTokenCache(t)
+
+	accessTokenCache = &cachedAccessToken{
+		ExpiresAt: time.Now(),
+		Key:       getAccessTokenCacheKey(),
+		Token: OAuth2TokenResponse{
+			AccessToken:  "expired",
+			RefreshToken: "refresh",
+		},
+	}
+
+	requestToken := func(ctx context.Context) (OAuth2TokenResponse, error) {
+		t.Fatal("token has been requested instead of refreshed")
+		return OAuth2TokenResponse{}, nil
+	}
+	refreshToken := func(ctx context.Context, refreshToken string) (OAuth2TokenResponse, error) {
+		if refreshToken != "refresh" {
+			t.Fatalf("unexpected refresh token %q", refreshToken)
+		}
+		return OAuth2TokenResponse{AccessToken: "refreshed"}, nil
+	}
+
+	token, err := getCachedAccessToken(context.Background(), requestToken, refreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.AccessToken != "refreshed" || token.RefreshToken != "refresh" {
+		t.Fatalf("unexpected token %+v", token)
+	}
+}
@@ -0,0 +1,142 @@
+// This file is part of the e.GPT distribution.
+// Copyright (c) Next.e.GO Mobile SE, Aachen, Germany (https://e-go-mobile.com/)
+//
+// e-gpt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, version 3.
+//
+// e-gpt is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+var commandName string
+var usageLedgerMutex sync.Mutex
+
+// UsageEntry is a single API call in the usage ledger
+type UsageEntry struct {
+	Command          string    `json:"command"`             // the name of the command, which did the call
+	CompletionTokens int       `json:"completionTokens"`    // number of tokens of the answer
+	Cost             *float64  `json:"cost"`                // the estimated cost in USD or `nil`, if unknown
+	Estimated        bool      `json:"estimated,omitempty"` // tokens have been counted locally
+	Model            string    `json:"model"`               // the model
+	PromptTokens     int       `json:"promptTokens"`        // number of tokens of the prompt
+	Provider         string    `json:"provider"`            // the name of the provider
+	Time             time.Time `json:"time"`                // the time of the call
+	TotalTokens      int       `json:"totalTokens"`         // total number of tokens
+}
+
+// AppendUsageEntry appends `entry` to the usage ledger.
+func AppendUsageEntry(entry UsageEntry) error {
+	filePath, err := GetUsageLedgerFilePath()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	usageLedgerMutex.Lock()
+	defer usageLedgerMutex.Unlock()
+
+	_, err = EnsureDir(filepath.Dir(filePath))
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(data, '\n'))
+
+	return err
+}
+
+// GetCommandName returns the name of the current command, which is set by SetCommandName().
+func GetCommandName() string {
+	return commandName
+}
+
+// GetUsageLedgerFilePath returns the path of the JSONL file, which contains the usage ledger.
+func GetUsageLedgerFilePath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return path.Join(homeDir, ".egpt/usage.jsonl"), nil
+}
+
+// IsUsageLedgerEnabled returns `true`, if API calls should be written to the usage ledger,
+// which is the default and can be changed by CHAT_USAGE_LEDGER.
+func IsUsageLedgerEnabled() bool {
+	val := strings.TrimSpace(os.Getenv("CHAT_USAGE_LEDGER"))
+	if val == "" {
+		return true
+	}
+
+	return IsTruthy(val)
+}
+
+// ReadUsageEntries reads all entries of the usage ledger.
+func ReadUsageEntries() ([]UsageEntry, error) {
+	filePath, err := GetUsageLedgerFilePath()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []UsageEntry{}
+
+	file, err := os.Open(filePath)
+	if os.IsNotExist(err) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var entry UsageEntry
+		err := json.Unmarshal([]byte(line), &entry)
+		if err != nil {
+			continue // ignore invalid lines
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, scanner.Err()
+}
+
+// SetCommandName sets the name of the current command, which is written to the usage ledger.
+func SetCommandName(name string) {
+	commandName = strings.TrimSpace(name)
+}
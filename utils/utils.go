@@ -18,6 +18,7 @@ package utils
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -33,46 +34,52 @@ import (
 )
 
 type OAuth2TokenResponse struct {
-	AccessToken string `json:"access_token"`
-	ExpiresIn   int    `json:"expires_in"`
-	TokenType   string `json:"token_type"`
+	AccessToken  string `json:"access_token"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	TokenType    string `json:"token_type"`
 }
 
-func getAccessTokenHttpRequest() (*http.Request, *[]byte, error) {
+func getAccessTokenHttpRequest(ctx context.Context, requestUrl string, body url.Values) (*http.Request, error) {
 	clientId := strings.TrimSpace(os.Getenv("CHAT_API_CLIENT_ID"))
 	clientSecret := strings.TrimSpace(os.Getenv("CHAT_API_CLIENT_SECRET"))
-	getApiTokenUrl := strings.TrimSpace(os.Getenv("OAUTH2_GET_TOKEN_URL"))
 
-	// build POST body
-	body := url.Values{}
-	body.Add("grant_type", "client_credentials")
-	body.Add("client_id", clientId)
-	body.Add("client_secret", clientSecret)
-
-	bodyStr := body.Encode()
+	// public clients, like in the device flow, have no secret
+	// and always send their ID in the body
+	useBasicAuth := clientSecret != "" && getOAuth2ClientAuth() == "basic"
+	if !useBasicAuth {
+		body.Set("client_id", clientId)
+		if clientSecret != "" {
+			body.Set("client_secret", clientSecret)
+		}
+	}
 
-	request, err := http.NewRequest("POST", getApiTokenUrl, bytes.NewBufferString(bodyStr))
+	request, err := http.NewRequestWithContext(ctx, "POST", requestUrl, bytes.NewBufferString(body.Encode()))
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	bodyData := []byte(bodyStr)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if useBasicAuth {
+		// s. RFC 6749, section 2.3.1
+		request.SetBasicAuth(url.QueryEscape(clientId), url.QueryEscape(clientSecret))
+	}
 
-	return request, &bodyData, nil
+	return request, nil
 }
 
-func getAccessTokenHttpResponse() (*http.Response, error) {
-	request, _, err := getAccessTokenHttpRequest()
+func getAccessTokenHttpResponse(ctx context.Context, requestUrl string, body url.Values) (*http.Response, error) {
+	request, err := getAccessTokenHttpRequest(ctx, requestUrl, body)
 	if err != nil {
 		return nil, err
 	}
 
-	httpClient := &http.Client{}
-
-	return httpClient.Do(request)
+	return GetHttpClient().Do(request)
 }
 
-func getResponseErrorMessage(message string, response *http.Response) string {
+// GetResponseErrorMessage creates an error message from `message`, the status code
+// and the (formatted) body of `response`.
+func GetResponseErrorMessage(message string, response *http.Response) string {
 	errorMessage := ""
 
 	if response != nil {
@@ -179,31 +186,11 @@ func ExecuteCommand(rawCommand string) (*exec.Cmd, error) {
 // GetAccessToken retrieves an OAuth2TokenResponse struct, which contains an access token and other
 // authentication details, from an API endpoint. If the response status code is 200, it attempts to
 // parse the response body as JSON and returns the tokenResponse struct. Otherwise, it returns an
-// error containing the response error message.
-func GetAccessToken() (OAuth2TokenResponse, error) {
-	var tokenResponse OAuth2TokenResponse
-
-	response, err := getAccessTokenHttpResponse()
-	if err != nil {
-		return tokenResponse, err
-	}
-
-	defer response.Body.Close()
-
-	if response.StatusCode == 200 {
-		bodyData, err := io.ReadAll(response.Body)
-		if err != nil {
-			return tokenResponse, err
-		}
-
-		err = json.Unmarshal(bodyData, &tokenResponse) // try parse as JSON
-
-		return tokenResponse, err
-	} else {
-		errorMessage := getResponseErrorMessage("The credentials do not work:", response)
-
-		return tokenResponse, errors.New(errorMessage)
-	}
+// error containing the response error message. The requests are canceled, if `ctx` is done or
+// the timeout of GetApiTimeout() is reached. Tokens are reused from the cache, until shortly before
+// they expire, and are renewed with their refresh token, if available.
+func GetAccessToken(ctx context.Context) (OAuth2TokenResponse, error) {
+	return getCachedAccessToken(ctx, requestAccessToken, refreshAccessToken)
 }
 
 // GetAndCheckInput retrieves user input from the command line using the GetInput function and panics
@@ -0,0 +1,42 @@
+// This file is part of the e.GPT distribution.
+// Copyright (c) Next.e.GO Mobile SE, Aachen, Germany (https://e-go-mobile.com/)
+//
+// e-gpt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, version 3.
+//
+// e-gpt is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"fmt"
+	"log"
+	"os"
+)
+
+var isVerbose bool
+
+// IsVerbose returns `true`, if additional information should be logged,
+// which is set by SetVerbose() or the CHAT_VERBOSE environment variable.
+func IsVerbose() bool {
+	return isVerbose || IsTruthy(os.Getenv("CHAT_VERBOSE"))
+}
+
+// LogVerbose writes a message to STDERR, if IsVerbose() is `true`.
+func LogVerbose(format string, a ...interface{}) {
+	if IsVerbose() {
+		log.Println("[VERBOSE]", fmt.Sprintf(format, a...))
+	}
+}
+
+// SetVerbose sets, if additional information should be logged.
+func SetVerbose(verbose bool) {
+	isVerbose = verbose
+}